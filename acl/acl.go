@@ -6,17 +6,32 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	core "github.com/uhppoted/uhppote-core/types"
 	lib "github.com/uhppoted/uhppoted-lib/acl"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
 )
 
 type ACL struct {
-	doors   []string
-	records []record
+	doors    []string
+	records  []record
+	failures []Failure
+}
+
+// Returns the members for which the rules could not be evaluated and the failure policy was
+// applied instead.
+func (acl *ACL) Failures() []Failure {
+	if acl != nil {
+		return acl.failures
+	}
+
+	return nil
 }
 
 func (acl *ACL) Updated(hash string) bool {
@@ -49,6 +64,86 @@ func (acl *ACL) Hash() string {
 	return ""
 }
 
+// Returns the (sorted) time profile IDs referenced by the ACL, keyed by door.
+func (acl *ACL) Profiles() map[string][]uint8 {
+	profiles := map[string][]uint8{}
+
+	if acl != nil {
+		for _, door := range acl.doors {
+			set := map[uint8]bool{}
+			for _, r := range acl.records {
+				if v, err := strconv.ParseUint(r.permission(door), 10, 8); err == nil {
+					set[uint8(v)] = true
+				}
+			}
+
+			if len(set) > 0 {
+				list := []uint8{}
+				for k := range set {
+					list = append(list, k)
+				}
+
+				slices.Sort(list)
+
+				profiles[door] = list
+			}
+		}
+	}
+
+	return profiles
+}
+
+// Card is the ACL entry for a member card, with the doors to which the card has been granted
+// access.
+type Card struct {
+	MemberID   uint32
+	Name       string
+	CardNumber uint32
+	StartDate  core.Date
+	EndDate    core.Date
+	Doors      []string
+}
+
+// Returns the cards in the ACL, ordered by card number.
+func (acl *ACL) Cards() []Card {
+	cards := []Card{}
+
+	if acl != nil {
+		for _, r := range acl.records {
+			card := Card{
+				MemberID:   r.MemberID,
+				Name:       r.Name,
+				CardNumber: r.CardNumber,
+				StartDate:  r.StartDate,
+				EndDate:    r.EndDate,
+				Doors:      []string{},
+			}
+
+			for _, door := range acl.doors {
+				if r.permission(door) != "N" {
+					card.Doors = append(card.Doors, door)
+				}
+			}
+
+			cards = append(cards, card)
+		}
+
+		sort.SliceStable(cards, func(i, j int) bool { return cards[i].CardNumber < cards[j].CardNumber })
+	}
+
+	return cards
+}
+
+// Columns selects the optional columns included in the tabular representation of an ACL.
+//
+// NOTE: the Name and Member ID columns are informational only - a table that includes either
+// of them is not accepted by the uhppoted-lib ACL functions.
+type Columns struct {
+	PIN      bool
+	Name     bool
+	MemberID bool
+}
+
 func (acl *ACL) AsTable() *lib.Table {
 	header, data := acl.asTable()
 
@@ -67,124 +162,61 @@ func (acl *ACL) AsTableWithPIN() *lib.Table {
 	}
 }
 
-func (acl *ACL) ToTSV(f io.Writer) error {
-	header, data := acl.asTable()
-
-	w := csv.NewWriter(f)
-	w.Comma = '\t'
+func (acl *ACL) AsTableWith(columns Columns) *lib.Table {
+	header, data := acl.table(columns)
 
-	w.Write(header)
-	for _, row := range data {
-		w.Write(row)
+	return &lib.Table{
+		Header:  header,
+		Records: data,
 	}
+}
 
-	w.Flush()
+func (acl *ACL) ToTSV(f io.Writer) error {
+	header, data := acl.asTable()
 
-	return nil
+	return toTSV(f, header, data)
 }
 
 func (acl *ACL) ToTSVWithPIN(f io.Writer) error {
 	header, data := acl.asTableWithPIN()
 
-	w := csv.NewWriter(f)
-	w.Comma = '\t'
-
-	w.Write(header)
-	for _, row := range data {
-		w.Write(row)
-	}
+	return toTSV(f, header, data)
+}
 
-	w.Flush()
+func (acl *ACL) ToTSVWith(f io.Writer, columns Columns) error {
+	header, data := acl.table(columns)
 
-	return nil
+	return toTSV(f, header, data)
 }
 
 func (acl *ACL) asTable() ([]string, [][]string) {
+	return acl.table(Columns{})
+}
+
+func (acl *ACL) asTableWithPIN() ([]string, [][]string) {
+	return acl.table(Columns{PIN: true})
+}
+
+func (acl *ACL) table(columns Columns) ([]string, [][]string) {
 	header := []string{}
 	data := [][]string{}
 
 	if acl != nil {
-		header = append(header, []string{
-			"Card Number",
-			"From",
-			"To",
-		}...)
-
-		header = append(header, acl.doors...)
-
-		sort.SliceStable(acl.records, func(i, j int) bool { return acl.records[i].CardNumber < acl.records[j].CardNumber })
-
-		for _, r := range acl.records {
-			row := []string{
-				fmt.Sprintf("%v", r.CardNumber),
-				fmt.Sprintf("%v", r.StartDate),
-				fmt.Sprintf("%v", r.EndDate),
-			}
-
-			for _, door := range acl.doors {
-				granted := false
-				revoked := false
-				profile := -1
-				d := normalise(door)
-
-				if _, ok := r.Granted["*"]; ok {
-					granted = true
-				}
-
-				for k, v := range r.Granted {
-					if d == normalise(k) {
-						switch vv := v.(type) {
-						case bool:
-							if vv {
-								granted = true
-							}
-
-						case int:
-							if vv >= 2 && vv <= 254 {
-								granted = true
-								profile = vv
-							}
-
-						}
-					}
-				}
-
-				if _, ok := r.Revoked["*"]; ok {
-					revoked = true
-				}
-
-				for k := range r.Revoked {
-					if d == normalise(k) {
-						revoked = true
-					}
-				}
-
-				if granted && !revoked {
-					if profile != -1 {
-						row = append(row, fmt.Sprintf("%v", profile))
-					} else {
-						row = append(row, "Y")
-					}
-				} else {
-					row = append(row, "N")
-				}
-			}
+		if columns.Name {
+			header = append(header, "Name")
+		}
 
-			data = append(data, row)
+		if columns.MemberID {
+			header = append(header, "Member ID")
 		}
-	}
 
-	return header, data
-}
+		header = append(header, "Card Number")
 
-func (acl *ACL) asTableWithPIN() ([]string, [][]string) {
-	header := []string{}
-	data := [][]string{}
+		if columns.PIN {
+			header = append(header, "PIN")
+		}
 
-	if acl != nil {
 		header = append(header, []string{
-			"Card Number",
-			"PIN",
 			"From",
 			"To",
 		}...)
@@ -194,68 +226,37 @@ func (acl *ACL) asTableWithPIN() ([]string, [][]string) {
 		sort.SliceStable(acl.records, func(i, j int) bool { return acl.records[i].CardNumber < acl.records[j].CardNumber })
 
 		for _, r := range acl.records {
-			var pin string
-
-			if r.PIN != 0 {
-				pin = fmt.Sprintf("%v", r.PIN)
-			} else {
-				pin = ""
-			}
+			row := []string{}
 
-			row := []string{
-				fmt.Sprintf("%v", r.CardNumber),
-				pin,
-				fmt.Sprintf("%v", r.StartDate),
-				fmt.Sprintf("%v", r.EndDate),
+			if columns.Name {
+				row = append(row, strings.TrimSpace(r.Name))
 			}
 
-			for _, door := range acl.doors {
-				granted := false
-				revoked := false
-				profile := -1
-				d := normalise(door)
-
-				if _, ok := r.Granted["*"]; ok {
-					granted = true
+			if columns.MemberID {
+				if r.MemberID != 0 {
+					row = append(row, fmt.Sprintf("%v", r.MemberID))
+				} else {
+					row = append(row, "")
 				}
+			}
 
-				for k, v := range r.Granted {
-					if d == normalise(k) {
-						switch vv := v.(type) {
-						case bool:
-							if vv {
-								granted = true
-							}
-
-						case int:
-							if vv >= 2 && vv <= 254 {
-								granted = true
-								profile = vv
-							}
-
-						}
-					}
-				}
+			row = append(row, fmt.Sprintf("%v", r.CardNumber))
 
-				if _, ok := r.Revoked["*"]; ok {
-					revoked = true
+			if columns.PIN {
+				if r.PIN != 0 {
+					row = append(row, fmt.Sprintf("%v", r.PIN))
+				} else {
+					row = append(row, "")
 				}
+			}
 
-				for k := range r.Revoked {
-					if d == normalise(k) {
-						revoked = true
-					}
-				}
+			row = append(row, []string{
+				fmt.Sprintf("%v", r.StartDate),
+				fmt.Sprintf("%v", r.EndDate),
+			}...)
 
-				if granted && !revoked {
-					if profile != -1 {
-						row = append(row, fmt.Sprintf("%v", profile))
-					} else {
-						row = append(row, "Y")
-					}
-				} else {
-					row = append(row, "N")
-				}
+			for _, door := range acl.doors {
+				row = append(row, r.permission(door))
 			}
 
 			data = append(data, row)
@@ -265,12 +266,30 @@ func (acl *ACL) asTableWithPIN() ([]string, [][]string) {
 	return header, data
 }
 
+func toTSV(f io.Writer, header []string, data [][]string) error {
+	w := csv.NewWriter(f)
+	w.Comma = '\t'
+
+	w.Write(header)
+	for _, row := range data {
+		w.Write(row)
+	}
+
+	w.Flush()
+
+	return w.Error()
+}
+
 func normalise(v string) string {
 	return strings.ToLower(strings.ReplaceAll(v, " ", ""))
 }
 
-func startOfYear() core.Date {
-	now := time.Now()
+func today(clock types.Clock) core.Date {
+	return clock.Today()
+}
+
+func startOfYear(clock types.Clock) core.Date {
+	now := clock.Now()
 	year := now.Year()
 	month := time.January
 	day := 1
@@ -278,11 +297,20 @@ func startOfYear() core.Date {
 	return core.ToDate(year, month, day)
 }
 
-func endOfYear() core.Date {
-	now := time.Now()
+func endOfYear(clock types.Clock) core.Date {
+	now := clock.Now()
 	year := now.Year()
 	month := time.December
 	day := 31
 
 	return core.ToDate(year, month, day)
 }
+
+// Returns the date N days after (or before, for negative N) the given date. Because card
+// end dates are inclusive, plusDays(date, 1) is the first day on which a card with end date
+// 'date' is no longer valid.
+func plusDays(date core.Date, days int) core.Date {
+	t := time.Time(date)
+
+	return core.ToDate(t.Year(), t.Month(), t.Day()+days)
+}
@@ -24,7 +24,7 @@ func TestAsTable(t *testing.T) {
 				Name:       "Albus Dumbledore",
 				CardNumber: 1000001,
 				StartDate:  core.MustParseDate("1880-02-29"),
-				EndDate:    endOfYear(),
+				EndDate:    endOfYear(nil),
 				Granted: map[string]any{
 					"Great Hall":      true,
 					"Whomping Willow": true,
@@ -37,14 +37,14 @@ func TestAsTable(t *testing.T) {
 				Name:       "Tom Riddle",
 				CardNumber: 2000001,
 				StartDate:  core.MustParseDate("1981-07-01"),
-				EndDate:    endOfYear(),
+				EndDate:    endOfYear(nil),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
 			},
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.MustParseDate("2021-06-30"),
 				Granted: map[string]any{
 					"Great Hall": true,
@@ -104,6 +104,112 @@ func TestAsTable(t *testing.T) {
 	}
 }
 
+func TestAsTableWithNameAndMemberID(t *testing.T) {
+	acl := ACL{
+		doors: []string{
+			"Great Hall",
+			"Dungeon",
+		},
+
+		records: []record{
+			record{
+				MemberID:   10001,
+				Name:       "Albus Dumbledore",
+				CardNumber: 1000001,
+				PIN:        7531,
+				StartDate:  core.MustParseDate("1880-02-29"),
+				EndDate:    core.MustParseDate("2021-12-31"),
+				Granted: map[string]any{
+					"Great Hall": true,
+					"Dungeon":    29,
+				},
+				Revoked: map[string]struct{}{},
+			},
+			record{
+				Name:       "Tom Riddle",
+				CardNumber: 2000001,
+				StartDate:  core.MustParseDate("1981-07-01"),
+				EndDate:    core.MustParseDate("2021-12-31"),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+		},
+	}
+
+	expected := lib.Table{
+		Header: []string{
+			"Name",
+			"Member ID",
+			"Card Number",
+			"PIN",
+			"From",
+			"To",
+			"Great Hall",
+			"Dungeon",
+		},
+
+		Records: [][]string{
+			[]string{"Albus Dumbledore", "10001", "1000001", "7531", "1880-02-29", "2021-12-31", "Y", "29"},
+			[]string{"Tom Riddle", "", "2000001", "", "1981-07-01", "2021-12-31", "N", "N"},
+		},
+	}
+
+	table := acl.AsTableWith(Columns{PIN: true, Name: true, MemberID: true})
+
+	if !reflect.DeepEqual(table.Header, expected.Header) {
+		t.Errorf("Invalid ACL table header - expected:%v, got:%v", expected.Header, table.Header)
+	}
+
+	if !reflect.DeepEqual(table.Records, expected.Records) {
+		t.Errorf("Invalid ACL table records\n   expected:%v\n   got:     %v", expected.Records, table.Records)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	acl := ACL{
+		doors: []string{
+			"Great Hall",
+			"Whomping Willow",
+			"Dungeon",
+			"Hogsmeade",
+		},
+
+		records: []record{
+			record{
+				CardNumber: 1000001,
+				Granted: map[string]any{
+					"Great Hall":      100,
+					"Whomping Willow": true,
+					"Dungeon":         29,
+				},
+				Revoked: map[string]struct{}{},
+			},
+			record{
+				CardNumber: 6000001,
+				Granted: map[string]any{
+					"Great Hall": 29,
+					"Dungeon":    29,
+					"Hogsmeade":  55,
+				},
+				Revoked: map[string]struct{}{
+					"Hogsmeade": struct{}{},
+				},
+			},
+		},
+	}
+
+	expected := map[string][]uint8{
+		"Great Hall": []uint8{29, 100},
+		"Dungeon":    []uint8{29},
+	}
+
+	profiles := acl.Profiles()
+
+	if !reflect.DeepEqual(profiles, expected) {
+		t.Errorf("Invalid ACL time profiles - expected:%v, got:%v", expected, profiles)
+	}
+}
+
 func TestHash(t *testing.T) {
 	dumbledore := record{
 		Name:       "Albus Dumbledore",
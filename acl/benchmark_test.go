@@ -0,0 +1,142 @@
+package acl
+
+import (
+	"fmt"
+	"testing"
+
+	core "github.com/uhppoted/uhppote-core/types"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var benchmarkRules = `
+// *** GRULES ***
+rule StartDate "Sets the start date to the 'registered' field" {
+     when
+		member.HasRegistered()
+	 then
+         permissions.SetStartDate(member.Registered);
+         Retract("StartDate");
+}
+
+rule EndDate "Sets the end date to the 'expires' field" {
+     when
+		member.HasExpires()
+	 then
+         permissions.SetEndDate(member.Expires);
+         Retract("EndDate");
+}
+
+rule Staff "Grants staff access to all doors" {
+     when
+		member.Is("Staff") && member.IsActive()
+	 then
+         permissions.Grant("Great Hall", "Gryffindor", "Dungeon", "Kitchen");
+         Retract("Staff");
+}
+
+rule Students "Grants students access to the common areas" {
+     when
+		member.HasGroup("Gryffindor") && member.IsActive()
+	 then
+         permissions.Grant("Great Hall", "Gryffindor");
+         permissions.Grant("Kitchen", 29);
+         Retract("Students");
+}
+
+rule Suspended "Revokes access for suspended members" {
+     when
+		member.IsSuspended()
+	 then
+         permissions.Revoke("*");
+         Retract("Suspended");
+}
+// *** END GRULES ***
+`
+
+// Generates a list of n members with a mix of membership levels, groups and states.
+func makeMembers(n int) types.Members {
+	gryffindor := types.Group{ID: 1, Name: "Gryffindor"}
+	members := types.Members{
+		Groups:  []types.Group{gryffindor},
+		Members: []types.Member{},
+	}
+
+	for i := range n {
+		card := types.CardNumber(1000000 + i)
+		m := types.Member{
+			ID:         uint32(i + 1),
+			Name:       fmt.Sprintf("Member %05v", i+1),
+			CardNumber: &card,
+			Active:     i%10 != 0,
+			Suspended:  i%97 == 0,
+			Registered: core.MustParseDate("2020-01-01"),
+			Expires:    core.MustParseDate("2026-12-31"),
+			Membership: types.Membership{ID: 1, Name: "Student"},
+			Groups:     map[uint32]types.Group{},
+		}
+
+		if i%20 == 0 {
+			m.Membership = types.Membership{ID: 2, Name: "Staff"}
+		} else {
+			m.Groups[gryffindor.ID] = gryffindor
+		}
+
+		members.Members = append(members.Members, m)
+	}
+
+	return members
+}
+
+func TestMakeACLWithWorkers(t *testing.T) {
+	members := makeMembers(1000)
+	doors := []string{"Great Hall", "Gryffindor", "Dungeon", "Kitchen"}
+
+	rules, err := NewRules([]byte(benchmarkRules), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	expected, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetWorkers(8)
+
+	acl, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if acl.Hash() != expected.Hash() {
+		t.Errorf("Incorrect ACL generated with workers - expected:%v, got:%v", expected.Hash(), acl.Hash())
+	}
+}
+
+// Benchmarks MakeACL for 10k members.
+//
+//	go test ./acl -run xxx -bench MakeACL
+func BenchmarkMakeACL(b *testing.B) {
+	members := makeMembers(10000)
+	doors := []string{"Great Hall", "Gryffindor", "Dungeon", "Kitchen"}
+
+	for _, workers := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("workers:%v", workers), func(b *testing.B) {
+			rules, err := NewRules([]byte(benchmarkRules), false)
+			if err != nil {
+				b.Fatalf("Unexpected error (%v)", err)
+			}
+
+			rules.SetWorkers(workers)
+
+			for b.Loop() {
+				if _, err := rules.MakeACL(members, doors); err != nil {
+					b.Fatalf("Unexpected error (%v)", err)
+				}
+			}
+
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(members.Members)), "ns/member")
+		})
+	}
+}
@@ -0,0 +1,609 @@
+package acl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	celtypes "github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+	core "github.com/uhppoted/uhppote-core/types"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// celMember is the member as a typed CEL object. Dates are CEL timestamps (midnight UTC) and
+// are the zero timestamp if not set.
+type celMember struct {
+	ID              int64             `cel:"id"`
+	Name            string            `cel:"name"`
+	CardNumber      int64             `cel:"card_number"`
+	PIN             int64             `cel:"pin"`
+	Active          bool              `cel:"active"`
+	Suspended       bool              `cel:"suspended"`
+	Registered      time.Time         `cel:"registered"`
+	Expires         time.Time         `cel:"expires"`
+	HasRegistered   bool              `cel:"has_registered"`
+	HasExpires      bool              `cel:"has_expires"`
+	DaysUntilExpiry int64             `cel:"days_until_expiry"`
+	Groups          []string          `cel:"groups"`
+	GroupIDs        []int64           `cel:"group_ids"`
+	Membership      string            `cel:"membership"`
+	MembershipID    int64             `cel:"membership_id"`
+	Fields          map[string]string `cel:"fields"`
+}
+
+// action is the opaque CEL value returned by the grant, revoke, start_date, end_date and
+// extend_end_date functions.
+type action struct {
+	function string
+	args     []any
+}
+
+var actionType = cel.OpaqueType("acl.action")
+
+// celRules is the Common Expression Language implementation of the ruleset interface.
+type celRules struct {
+	rules []celRule
+}
+
+type celRule struct {
+	name        string
+	description string
+	salience    int
+	line        int
+	source      [2]string
+	condition   *cel.Ast
+	actions     *cel.Ast
+	when        cel.Program
+	then        cel.Program
+}
+
+// NewCELRules parses a CEL rules file. A CEL rules file is a list of rules of the form:
+//
+//	# description
+//	Name [salience N]: <condition> => <action>, <action>, ...
+//
+// where the condition is a CEL boolean expression of the 'member' object and the actions are
+// grant, revoke, start_date, end_date and extend_end_date. A rule starts at the beginning of
+// a line and continuation lines are indented. Comment lines immediately preceding a rule are
+// used as the rule description. The rules are evaluated once for each member, in order of
+// decreasing salience and then in file order.
+func NewCELRules(ruleset []byte, debug bool) (*Rules, error) {
+	env, err := celEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	c := celRules{
+		rules: []celRule{},
+	}
+
+	parsed, err := parseCEL(ruleset)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, r := range parsed {
+		if names[r.name] {
+			return nil, fmt.Errorf("line %v: duplicate rule name '%v'", r.line, r.name)
+		}
+
+		names[r.name] = true
+
+		if err := r.compile(env); err != nil {
+			return nil, err
+		}
+
+		c.rules = append(c.rules, r)
+	}
+
+	slices.SortStableFunc(c.rules, func(p, q celRule) int { return q.salience - p.salience })
+
+	hash := sha256.Sum256(ruleset)
+	rules := Rules{
+		hash:    hash[:],
+		ruleset: &c,
+	}
+
+	return &rules, nil
+}
+
+func celEnv() (*cel.Env, error) {
+	grant := func(args ...ref.Val) ref.Val {
+		a := action{function: "grant"}
+		for _, arg := range args {
+			a.args = append(a.args, arg.Value())
+		}
+
+		return a
+	}
+
+	date := func(function string) func(ref.Val) ref.Val {
+		return func(v ref.Val) ref.Val {
+			return action{function: function, args: []any{v.Value()}}
+		}
+	}
+
+	member := func(f func(celMember, any) bool) func(ref.Val, ref.Val) ref.Val {
+		return func(m, v ref.Val) ref.Val {
+			if member, ok := m.Value().(celMember); ok {
+				return celtypes.Bool(f(member, v.Value()))
+			}
+
+			return celtypes.False
+		}
+	}
+
+	memberType := cel.ObjectType("acl.celMember")
+
+	return cel.NewEnv(
+		ext.NativeTypes(reflect.TypeFor[celMember](), ext.ParseStructTags(true)),
+		cel.Variable("member", memberType),
+
+		cel.Function("has_group",
+			cel.MemberOverload("member_has_group_string", []*cel.Type{memberType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(member(celMember.hasGroup))),
+			cel.MemberOverload("member_has_group_int", []*cel.Type{memberType, cel.IntType}, cel.BoolType,
+				cel.BinaryBinding(member(celMember.hasGroup)))),
+
+		cel.Function("is",
+			cel.MemberOverload("member_is_string", []*cel.Type{memberType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(member(celMember.is))),
+			cel.MemberOverload("member_is_int", []*cel.Type{memberType, cel.IntType}, cel.BoolType,
+				cel.BinaryBinding(member(celMember.is)))),
+
+		cel.Function("grant",
+			cel.Overload("grant_string", []*cel.Type{cel.StringType}, actionType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val { return grant(v) })),
+			cel.Overload("grant_string_int", []*cel.Type{cel.StringType, cel.IntType}, actionType,
+				cel.BinaryBinding(func(u, v ref.Val) ref.Val { return grant(u, v) })),
+			cel.Overload("grant_string_string", []*cel.Type{cel.StringType, cel.StringType}, actionType,
+				cel.BinaryBinding(func(u, v ref.Val) ref.Val { return grant(u, v) }))),
+
+		cel.Function("revoke",
+			cel.Overload("revoke_string", []*cel.Type{cel.StringType}, actionType,
+				cel.UnaryBinding(date("revoke")))),
+
+		cel.Function("start_date",
+			cel.Overload("start_date_timestamp", []*cel.Type{cel.TimestampType}, actionType,
+				cel.UnaryBinding(date("start_date"))),
+			cel.Overload("start_date_string", []*cel.Type{cel.StringType}, actionType,
+				cel.UnaryBinding(date("start_date")))),
+
+		cel.Function("end_date",
+			cel.Overload("end_date_timestamp", []*cel.Type{cel.TimestampType}, actionType,
+				cel.UnaryBinding(date("end_date"))),
+			cel.Overload("end_date_string", []*cel.Type{cel.StringType}, actionType,
+				cel.UnaryBinding(date("end_date")))),
+
+		cel.Function("extend_end_date",
+			cel.Overload("extend_end_date_int", []*cel.Type{cel.IntType}, actionType,
+				cel.UnaryBinding(date("extend_end_date")))),
+	)
+}
+
+func parseCEL(ruleset []byte) ([]celRule, error) {
+	rules := []celRule{}
+	header := regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?:\s+salience\s+(-?[0-9]+))?\s*:(.*)$`)
+	comments := []string{}
+
+	var current *celRule
+	var body strings.Builder
+
+	flush := func() error {
+		if current != nil {
+			condition, actions, ok := split(body.String())
+			if !ok {
+				return fmt.Errorf("line %v: rule %v is missing '=>'", current.line, current.name)
+			}
+
+			current.source = [2]string{condition, actions}
+			rules = append(rules, *current)
+		}
+
+		current = nil
+		body.Reset()
+
+		return nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(ruleset))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//"):
+			if text[0] != ' ' && text[0] != '\t' {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+
+				comments = append(comments, strings.TrimSpace(strings.TrimLeft(trimmed, "#/")))
+			}
+
+		case trimmed == "":
+			comments = comments[:0]
+
+		case header.MatchString(text):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+			match := header.FindStringSubmatch(text)
+			salience := 0
+			if match[2] != "" {
+				salience, _ = strconv.Atoi(match[2])
+			}
+
+			current = &celRule{
+				name:        match[1],
+				description: strings.Join(slices.DeleteFunc(comments, func(c string) bool { return c == "" }), " "),
+				salience:    salience,
+				line:        line,
+			}
+
+			body.WriteString(match[3])
+			comments = []string{}
+
+		case current != nil && (text[0] == ' ' || text[0] == '\t'):
+			body.WriteString("\n")
+			body.WriteString(text)
+
+		default:
+			return nil, fmt.Errorf("line %v: invalid rule '%v'", line, trimmed)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// Splits a rule into the condition and actions at the first '=>' that is not in a string literal.
+func split(rule string) (string, string, bool) {
+	var quote rune
+	escaped := false
+
+	for i, ch := range rule {
+		switch {
+		case escaped:
+			escaped = false
+
+		case ch == '\\' && quote != 0:
+			escaped = true
+
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+
+		case ch == '"' || ch == '\'':
+			quote = ch
+
+		case ch == '=' && strings.HasPrefix(rule[i:], "=>"):
+			return strings.TrimSpace(rule[:i]), strings.TrimSpace(rule[i+2:]), true
+		}
+	}
+
+	return "", "", false
+}
+
+func (r *celRule) compile(env *cel.Env) error {
+	condition, issues := env.Compile(r.source[0])
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("line %v: rule %v: invalid condition (%v)", r.line, r.name, issues.Err())
+	} else if !condition.OutputType().IsExactType(cel.BoolType) {
+		return fmt.Errorf("line %v: rule %v: condition must be a bool (is %v)", r.line, r.name, condition.OutputType())
+	}
+
+	actions, issues := env.Compile("[" + r.source[1] + "]")
+	if issues != nil && issues.Err() != nil {
+		return fmt.Errorf("line %v: rule %v: invalid actions (%v)", r.line, r.name, issues.Err())
+	} else if !actions.OutputType().IsExactType(cel.ListType(actionType)) {
+		return fmt.Errorf("line %v: rule %v: actions must be grant, revoke, start_date, end_date or extend_end_date", r.line, r.name)
+	}
+
+	when, err := env.Program(condition, cel.InterruptCheckFrequency(100))
+	if err != nil {
+		return fmt.Errorf("line %v: rule %v: %v", r.line, r.name, err)
+	}
+
+	then, err := env.Program(actions, cel.InterruptCheckFrequency(100))
+	if err != nil {
+		return fmt.Errorf("line %v: rule %v: %v", r.line, r.name, err)
+	}
+
+	r.condition = condition
+	r.actions = actions
+	r.when = when
+	r.then = then
+
+	return nil
+}
+
+// Evaluates the rules for a member. The rules are only evaluated once so the max. cycles
+// limit does not apply.
+func (c *celRules) eval(m types.Member, r *record, evaluation Evaluation, trace tracer) error {
+	ctx := context.Background()
+	if timeout := evaluation.Timeout; timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	vars := map[string]any{
+		"member": newCELMember(m),
+	}
+
+	for i, rule := range c.rules {
+		if v, _, err := rule.when.ContextEval(ctx, vars); err != nil {
+			return fmt.Errorf("rule %v: %v", rule.name, err)
+		} else if v != celtypes.True {
+			continue
+		}
+
+		v, _, err := rule.then.ContextEval(ctx, vars)
+		if err != nil {
+			return fmt.Errorf("rule %v: %v", rule.name, err)
+		}
+
+		if trace != nil {
+			trace.execute(uint64(i+1), rule.name, rule.description)
+		}
+
+		if list, ok := v.(traits.Lister); ok {
+			for it := list.Iterator(); it.HasNext() == celtypes.True; {
+				if a, ok := it.Next().(action); ok {
+					a.apply(r)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (a action) apply(r *record) {
+	switch a.function {
+	case "grant":
+		r.Grant(a.args...)
+
+	case "revoke":
+		if door, ok := a.args[0].(string); ok {
+			r.Revoke(door)
+		}
+
+	case "start_date":
+		if t, ok := a.args[0].(time.Time); ok {
+			r.SetStartDate(fromTimestamp(t))
+		} else {
+			r.SetStartDate(a.args[0])
+		}
+
+	case "end_date":
+		if t, ok := a.args[0].(time.Time); ok {
+			r.SetEndDate(fromTimestamp(t))
+		} else {
+			r.SetEndDate(a.args[0])
+		}
+
+	case "extend_end_date":
+		r.ExtendEndDate(a.args[0])
+	}
+}
+
+func (a action) ConvertToNative(t reflect.Type) (any, error) {
+	return nil, fmt.Errorf("unsupported conversion from %v to %v", actionType, t)
+}
+
+func (a action) ConvertToType(t ref.Type) ref.Val {
+	if t == celtypes.TypeType {
+		return actionType
+	}
+
+	return celtypes.NewErr("unsupported conversion from %v to %v", actionType, t)
+}
+
+func (a action) Equal(other ref.Val) ref.Val {
+	return celtypes.Bool(reflect.DeepEqual(a, other))
+}
+
+func (a action) Type() ref.Type {
+	return actionType
+}
+
+func (a action) Value() any {
+	return a
+}
+
+func (c *celRules) lint(v Vocabulary, schedules Schedules) []string {
+	warnings := []string{}
+
+	rules := slices.Clone(c.rules)
+	slices.SortFunc(rules, func(p, q celRule) int { return strings.Compare(p.name, q.name) })
+
+	for _, rule := range rules {
+		l := linter{
+			vocabulary: v,
+			schedules:  schedules,
+			warnings:   []string{},
+		}
+
+		literal := func(e celast.Expr) any {
+			if e.Kind() == celast.LiteralKind {
+				return e.AsLiteral().Value()
+			}
+
+			return nil
+		}
+
+		field := func(e celast.Expr) string {
+			if e.Kind() == celast.SelectKind && e.AsSelect().Operand().Kind() == celast.IdentKind && e.AsSelect().Operand().AsIdent() == "member" {
+				return e.AsSelect().FieldName()
+			}
+
+			return ""
+		}
+
+		// ... groups and membership levels
+		for _, e := range celast.MatchDescendants(celast.NavigateAST(rule.condition.NativeRep()), celast.KindMatcher(celast.CallKind)) {
+			call := e.AsCall()
+			args := call.Args()
+
+			// ... member.groups and member.membership are the Wild Apricot names and are compared
+			//     exactly, unlike member.has_group(...) and member.is(...)
+			switch {
+			case call.FunctionName() == operators.In && len(args) == 2:
+				if f := field(args[1]); f == "groups" || f == "group_ids" {
+					l.groups([]any{literal(args[0])}, exact)
+				}
+
+			case call.FunctionName() == operators.Equals && len(args) == 2:
+				for _, pair := range [][2]celast.Expr{{args[0], args[1]}, {args[1], args[0]}} {
+					if f := field(pair[0]); f == "membership" || f == "membership_id" {
+						l.memberships([]any{literal(pair[1])}, exact)
+					}
+				}
+
+			case call.FunctionName() == "has_group" && call.IsMemberFunction() && len(args) == 1:
+				l.groups([]any{literal(args[0])}, same)
+
+			case call.FunctionName() == "is" && call.IsMemberFunction() && len(args) == 1:
+				l.memberships([]any{literal(args[0])}, same)
+			}
+		}
+
+		// ... doors
+		for _, e := range celast.MatchDescendants(celast.NavigateAST(rule.actions.NativeRep()), celast.KindMatcher(celast.CallKind)) {
+			call := e.AsCall()
+			args := []any{}
+			for _, arg := range call.Args() {
+				args = append(args, literal(arg))
+			}
+
+			switch call.FunctionName() {
+			case "grant":
+				l.doors(l.granted(args))
+
+			case "revoke":
+				l.doors(args)
+			}
+		}
+
+		for _, w := range l.warnings {
+			warnings = append(warnings, fmt.Sprintf("rule %v: %v", rule.name, w))
+		}
+	}
+
+	return warnings
+}
+
+func newCELMember(m types.Member) celMember {
+	member := celMember{
+		ID:              int64(m.ID),
+		Name:            m.Name,
+		PIN:             int64(m.PIN),
+		Active:          m.Active,
+		Suspended:       m.Suspended,
+		HasRegistered:   m.HasRegistered(),
+		HasExpires:      m.HasExpires(),
+		DaysUntilExpiry: m.DaysUntilExpiry(),
+		Groups:          []string{},
+		GroupIDs:        []int64{},
+		Membership:      m.Membership.Name,
+		MembershipID:    int64(m.Membership.ID),
+		Fields:          map[string]string{},
+	}
+
+	if m.CardNumber != nil {
+		member.CardNumber = int64(*m.CardNumber)
+	}
+
+	if !m.Registered.IsZero() {
+		member.Registered = toTimestamp(m.Registered)
+	}
+
+	if !m.Expires.IsZero() {
+		member.Expires = toTimestamp(m.Expires)
+	}
+
+	for _, g := range m.Groups {
+		member.Groups = append(member.Groups, g.Name)
+		member.GroupIDs = append(member.GroupIDs, int64(g.ID))
+	}
+
+	slices.Sort(member.Groups)
+	slices.Sort(member.GroupIDs)
+
+	for _, f := range m.Fields {
+		member.Fields[f.Name] = fmt.Sprintf("%v", f.Value)
+	}
+
+	return member
+}
+
+// Returns true if the member is in the group, matching group names the same way as the grule
+// HasGroup function i.e. ignoring case and any non-alphanumeric characters.
+func (m celMember) hasGroup(group any) bool {
+	switch v := group.(type) {
+	case string:
+		return slices.ContainsFunc(m.Groups, func(g string) bool { return same(g, v) })
+
+	case int64:
+		return slices.Contains(m.GroupIDs, v)
+	}
+
+	return false
+}
+
+// Returns true if the member has the membership level, matching level names the same way as
+// the grule Is function i.e. ignoring case and any non-alphanumeric characters.
+func (m celMember) is(membership any) bool {
+	switch v := membership.(type) {
+	case string:
+		return same(m.Membership, v)
+
+	case int64:
+		return m.MembershipID == v
+	}
+
+	return false
+}
+
+func toTimestamp(date core.Date) time.Time {
+	t := time.Time(date)
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func fromTimestamp(t time.Time) core.Date {
+	t = t.UTC()
+
+	return core.ToDate(t.Year(), t.Month(), t.Day())
+}
@@ -0,0 +1,199 @@
+package acl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var celrules = `
+# Grants staff access to all doors
+Staff salience 10: member.membership == "Staff" => grant("*"), start_date(member.registered)
+
+# Grants active students access to the common areas
+Students: member.active && member.membership in ["Student", "Stduent"]
+    => grant("Great Hall"),
+       grant("Whomping Willow", 29),
+       end_date(member.expires)
+
+Everyone: true => grant("Hogsmeade")
+
+// Revokes all access for suspended members
+Suspended salience -10: member.suspended => revoke("*")
+`
+
+func TestCELRules(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, admin, harry, hermione, voldemort},
+	}
+
+	doors := []string{"Great Hall", "Hogsmeade", "Whomping Willow"}
+
+	expected := [][]string{
+		{"1000001", "Y", "Y", "Y"},
+		{"2000001", "N", "N", "N"},
+		{"6000001", "Y", "Y", "29"},
+		{"6000002", "N", "Y", "N"},
+	}
+
+	rules, err := ParseRules("wild-apricot.cel", []byte(celrules), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetWorkers(2)
+
+	acl, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if rows := permissions(acl); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, rows)
+	}
+
+	table := acl.AsTable()
+	if start := table.Records[0][1]; start != "1880-02-29" {
+		t.Errorf("Incorrect start date - expected:%v, got:%v", "1880-02-29", start)
+	}
+
+	if end := table.Records[2][2]; end != "2021-06-30" {
+		t.Errorf("Incorrect end date - expected:%v, got:%v", "2021-06-30", end)
+	}
+}
+
+func TestCELRulesExplain(t *testing.T) {
+	expected := []Step{
+		{Cycle: 2, Rule: "Students", Description: "Grants active students access to the common areas", Changes: []string{"end date 2026-12-31 -> 2021-06-30", "granted Great Hall", "granted Whomping Willow (time profile 29)"}},
+		{Cycle: 3, Rule: "Everyone", Changes: []string{"granted Hogsmeade"}},
+	}
+
+	rules, err := NewCELRules([]byte(celrules), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	x, err := rules.Explain(harry, []string{"Great Hall", "Hogsmeade", "Whomping Willow"})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	// ... ignore default end date
+	for i := range x.Rules {
+		if len(x.Rules[i].Changes) > 0 && strings.HasPrefix(x.Rules[i].Changes[0], "end date ") {
+			x.Rules[i].Changes[0] = "end date 2026-12-31 -> 2021-06-30"
+		}
+	}
+
+	if !reflect.DeepEqual(x.Rules, expected) {
+		t.Errorf("Incorrect explanation\n   expected:%+v\n   got:     %+v", expected, x.Rules)
+	}
+}
+
+func TestCELRulesLint(t *testing.T) {
+	expected := []string{
+		"rule Staff: unknown membership level 'Staff'",
+		"rule Students: unknown door 'Whomping Willow'",
+	}
+
+	rules, err := NewCELRules([]byte(celrules), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	vocabulary := Vocabulary{
+		Doors:       []string{"Great Hall", "Hogsmeade"},
+		Memberships: []types.Membership{{ID: 545454, Name: "Student"}},
+	}
+
+	if warnings := rules.Lint(vocabulary); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Incorrect lint warnings\n   expected:%v\n   got:     %v", expected, warnings)
+	}
+}
+
+func TestCELRulesHasGroupAndIs(t *testing.T) {
+	ruleset := `
+Exact: "quidditch team" in member.groups => grant("Great Hall")
+HasGroup: member.has_group("quidditch team") => grant("Hogsmeade")
+HasGroupID: member.has_group(99) => grant("Whomping Willow")
+Is: member.is("STDUENT") && member.is(545454) => grant("Dungeon")
+`
+
+	member := harry
+	member.Groups = map[uint32]types.Group{99: {ID: 99, Name: "Quidditch-Team"}}
+
+	members := types.Members{
+		Members: []types.Member{member},
+	}
+
+	doors := []string{"Dungeon", "Great Hall", "Hogsmeade", "Whomping Willow"}
+
+	expected := [][]string{
+		{"6000001", "Y", "N", "Y", "Y"},
+	}
+
+	rules, err := NewCELRules([]byte(ruleset), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	acl, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if rows := permissions(acl); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, rows)
+	}
+}
+
+func TestCELRulesLintGroups(t *testing.T) {
+	ruleset := `
+Exact: "quidditch team" in member.groups => grant("Great Hall")
+HasGroup: member.has_group("quidditch team") => grant("Great Hall")
+Membership: member.membership == "student" => grant("Great Hall")
+Is: member.is("student") => grant("Great Hall")
+`
+
+	expected := []string{
+		"rule Exact: unknown group 'quidditch team'",
+		"rule Membership: unknown membership level 'student'",
+	}
+
+	rules, err := NewCELRules([]byte(ruleset), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	vocabulary := Vocabulary{
+		Doors:       []string{"Great Hall"},
+		Groups:      []types.Group{{ID: 99, Name: "Quidditch-Team"}},
+		Memberships: []types.Membership{{ID: 545454, Name: "Student"}},
+	}
+
+	if warnings := rules.Lint(vocabulary); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Incorrect lint warnings\n   expected:%v\n   got:     %v", expected, warnings)
+	}
+}
+
+func TestCELRulesWithInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"missing =>":       `Staff: member.membership == "Staff"`,
+		"unknown field":    `Staff: member.level == "Staff" => grant("*")`,
+		"not bool":         `Staff: member.membership => grant("*")`,
+		"unknown action":   `Staff: true => allow("*")`,
+		"invalid action":   `Staff: true => "*"`,
+		"duplicate rule":   "Staff: true => grant(\"*\")\nStaff: true => grant(\"*\")",
+		"unindented line":  "Staff: true\n=> grant(\"*\")",
+		"wrong arg type":   `Staff: true => end_date(member.membership_id)`,
+		"invalid salience": `Staff salience high: true => grant("*")`,
+	}
+
+	for k, v := range tests {
+		if _, err := NewCELRules([]byte(v), false); err == nil {
+			t.Errorf("%v: expected error, got %v", k, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package acl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+)
+
+// Evaluation defines the limits on evaluating the rules for a single member and the policy
+// applied if the evaluation fails (e.g. a rule that does not Retract itself and loops until
+// the cycle limit):
+//
+//   - abort:          fails the entire ACL generation (legacy behaviour)
+//   - deny-all:       the member's card is included in the ACL without access to any doors
+//   - keep-previous:  the member's card keeps the entry from the previous ACL (deny-all if the
+//     card is not in the previous ACL)
+//
+// A zero MaxCycles uses the grule default (5000) and a zero Timeout does not limit the
+// evaluation time.
+type Evaluation struct {
+	MaxCycles uint64
+	Timeout   time.Duration
+	OnError   FailurePolicy
+}
+
+type FailurePolicy int
+
+const (
+	Abort FailurePolicy = iota
+	DenyAll
+	KeepPrevious
+)
+
+func (p FailurePolicy) String() string {
+	if names := [...]string{"abort", "deny-all", "keep-previous"}; int(p) >= 0 && int(p) < len(names) {
+		return names[p]
+	}
+
+	return fmt.Sprintf("unknown (%d)", int(p))
+}
+
+func ParseFailurePolicy(s string) (FailurePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "abort":
+		return Abort, nil
+
+	case "deny-all", "deny":
+		return DenyAll, nil
+
+	case "keep-previous", "keep":
+		return KeepPrevious, nil
+	}
+
+	return Abort, fmt.Errorf("invalid failure policy '%v'", s)
+}
+
+// Failure records a member for which the rules could not be evaluated.
+type Failure struct {
+	MemberID   uint32
+	Name       string
+	CardNumber uint32
+	Err        error
+}
+
+func (f Failure) Error() string {
+	return fmt.Sprintf("%v (card %v): %v", f.Name, f.CardNumber, f.Err)
+}
+
+// Sets the evaluation limits and failure policy.
+func (rules *Rules) SetEvaluation(evaluation Evaluation) {
+	if rules != nil {
+		rules.evaluation = evaluation
+	}
+}
+
+// Sets the previous ACL used by the keep-previous failure policy, from a table in the format
+// generated by uhppoted-lib MakeTable i.e. Card Number, From, To and a column for each door
+// (a PIN column is ignored).
+func (rules *Rules) SetPrevious(table *lib.Table) error {
+	if rules == nil || table == nil {
+		return nil
+	}
+
+	previous := map[uint32]record{}
+	columns := map[string]int{}
+	doors := map[int]string{}
+
+	for i, h := range table.Header {
+		switch k := normalise(h); k {
+		case "cardnumber", "pin", "from", "to":
+			columns[k] = i
+
+		default:
+			doors[i] = k
+		}
+	}
+
+	if _, ok := columns["cardnumber"]; !ok {
+		return fmt.Errorf("previous ACL is missing 'Card Number' column")
+	}
+
+	field := func(row []string, k string) string {
+		if ix, ok := columns[k]; ok && ix < len(row) {
+			return strings.TrimSpace(row[ix])
+		}
+
+		return ""
+	}
+
+	for _, row := range table.Records {
+		card, err := strconv.ParseUint(field(row, "cardnumber"), 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid card number in previous ACL (%v)", err)
+		}
+
+		r := record{
+			CardNumber: uint32(card),
+			Granted:    map[string]any{},
+			Revoked:    map[string]struct{}{},
+		}
+
+		if date, err := core.ParseDate(field(row, "from")); err == nil {
+			r.StartDate = date
+		}
+
+		if date, err := core.ParseDate(field(row, "to")); err == nil {
+			r.EndDate = date
+		}
+
+		for ix, door := range doors {
+			if ix < len(row) {
+				switch v := strings.TrimSpace(row[ix]); {
+				case v == "Y":
+					r.Granted[door] = true
+
+				case v != "" && v != "N":
+					if profile, err := strconv.Atoi(v); err == nil {
+						r.Granted[door] = profile
+					}
+				}
+			}
+		}
+
+		previous[r.CardNumber] = r
+	}
+
+	rules.previous = previous
+
+	return nil
+}
+
+// Applies the failure policy to a record for which the rules could not be evaluated. The card
+// number, PIN and start and end dates are first reset to the member defaults, discarding any
+// changes made by the rules before the error.
+func (rules *Rules) fallback(r *record, defaults record) {
+	r.CardNumber = defaults.CardNumber
+	r.PIN = defaults.PIN
+	r.StartDate = defaults.StartDate
+	r.EndDate = defaults.EndDate
+
+	if p, ok := rules.previous[r.CardNumber]; ok && r.CardNumber != 0 && rules.evaluation.OnError == KeepPrevious {
+		r.StartDate = p.StartDate
+		r.EndDate = p.EndDate
+		r.Granted = p.Granted
+		r.Revoked = map[string]struct{}{}
+	} else {
+		r.Granted = map[string]any{}
+		r.Revoked = map[string]struct{}{"*": {}}
+	}
+}
@@ -0,0 +1,228 @@
+package acl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var loop = `
+// *** GRULES ***
+rule Grant "Grants permission to the Great Hall" salience 10 {
+     when
+		member.HasCardNumber(1000001) || member.HasCardNumber(6000001)
+	 then
+         permissions.Grant("Great Hall");
+         Retract("Grant");
+}
+
+rule Loop "Grants permission to the Whomping Willow but never retracts itself" {
+     when
+		member.HasCardNumber(6000001)
+	 then
+         permissions.Grant("Whomping Willow");
+}
+// *** END GRULES ***
+`
+
+func TestMakeACLWithAbortPolicy(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry},
+	}
+
+	rules, err := NewRules([]byte(loop), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetEvaluation(Evaluation{MaxCycles: 100})
+
+	if _, err := rules.MakeACL(members, []string{"Great Hall", "Whomping Willow"}); err == nil {
+		t.Errorf("Expected error evaluating looping rule, got %v", err)
+	}
+}
+
+func TestMakeACLWithDenyAllPolicy(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry},
+	}
+
+	expected := [][]string{
+		{"1000001", "Y", "N"},
+		{"6000001", "N", "N"},
+	}
+
+	rules, err := NewRules([]byte(loop), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetEvaluation(Evaluation{MaxCycles: 100, OnError: DenyAll})
+
+	acl, err := rules.MakeACL(members, []string{"Great Hall", "Whomping Willow"})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if got := permissions(acl); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, got)
+	}
+
+	if failures := acl.Failures(); len(failures) != 1 || failures[0].CardNumber != 6000001 || failures[0].Name != "Harry Potter" {
+		t.Errorf("Incorrect failures\n   expected:%v\n   got:     %v", "Harry Potter (card 6000001)", failures)
+	}
+}
+
+func TestMakeACLWithDenyAllPolicyResetsRecord(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry},
+	}
+
+	ruleset := `
+// *** GRULES ***
+rule Loop "Updates the card and never retracts itself" {
+     when
+		member.HasCardNumber(6000001)
+	 then
+         permissions.SetCardNumber("7000001");
+         permissions.SetStartDate("2030-01-01");
+         permissions.SetEndDate("2030-12-31");
+         permissions.Grant("Whomping Willow");
+}
+// *** END GRULES ***
+`
+
+	expected := [][]string{
+		{"1000001", "N", "N"},
+		{"6000001", "N", "N"},
+	}
+
+	rules, err := NewRules([]byte(ruleset), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetEvaluation(Evaluation{MaxCycles: 100, OnError: DenyAll})
+
+	acl, err := rules.MakeACL(members, []string{"Great Hall", "Whomping Willow"})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if got := permissions(acl); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, got)
+	}
+
+	start, end := rules.validity.dates(harry, rules.clock)
+	if table := acl.AsTable(); table.Records[1][1] != start.String() || table.Records[1][2] != end.String() {
+		t.Errorf("Incorrect start/end dates\n   expected:%v %v\n   got:     %v", start, end, table.Records[1][1:3])
+	}
+}
+
+func TestMakeACLWithKeepPreviousPolicy(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry},
+	}
+
+	previous := lib.Table{
+		Header: []string{"Card Number", "From", "To", "Great Hall", "Whomping Willow"},
+		Records: [][]string{
+			{"6000001", "2021-01-01", "2021-06-30", "N", "29"},
+		},
+	}
+
+	expected := [][]string{
+		{"1000001", "Y", "N"},
+		{"6000001", "N", "29"},
+	}
+
+	rules, err := NewRules([]byte(loop), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetEvaluation(Evaluation{MaxCycles: 100, OnError: KeepPrevious})
+
+	if err := rules.SetPrevious(&previous); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	acl, err := rules.MakeACL(members, []string{"Great Hall", "Whomping Willow"})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if got := permissions(acl); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, got)
+	}
+
+	if table := acl.AsTable(); table.Records[1][1] != "2021-01-01" || table.Records[1][2] != "2021-06-30" {
+		t.Errorf("Incorrect start/end dates\n   expected:%v\n   got:     %v", "2021-01-01 2021-06-30", table.Records[1][1:3])
+	}
+
+	if failures := acl.Failures(); len(failures) != 1 || failures[0].CardNumber != 6000001 {
+		t.Errorf("Incorrect failures\n   expected:%v\n   got:     %v", "Harry Potter (card 6000001)", failures)
+	}
+}
+
+func TestMakeACLWithTimeout(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry},
+	}
+
+	rules, err := NewRules([]byte(loop), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetEvaluation(Evaluation{MaxCycles: 1<<63 - 1, Timeout: 100 * time.Millisecond, OnError: DenyAll})
+	rules.SetWorkers(2)
+
+	acl, err := rules.MakeACL(members, []string{"Great Hall", "Whomping Willow"})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if failures := acl.Failures(); len(failures) != 1 || failures[0].CardNumber != 6000001 {
+		t.Errorf("Incorrect failures\n   expected:%v\n   got:     %v", "Harry Potter (card 6000001)", failures)
+	}
+}
+
+func TestParseFailurePolicy(t *testing.T) {
+	tests := map[string]FailurePolicy{
+		"":              Abort,
+		"abort":         Abort,
+		"deny-all":      DenyAll,
+		"Keep-Previous": KeepPrevious,
+	}
+
+	for s, expected := range tests {
+		if policy, err := ParseFailurePolicy(s); err != nil {
+			t.Errorf("Unexpected error parsing failure policy '%v' (%v)", s, err)
+		} else if policy != expected {
+			t.Errorf("Incorrect failure policy for '%v' - expected:%v, got:%v", s, expected, policy)
+		}
+	}
+
+	if _, err := ParseFailurePolicy("ignore"); err == nil {
+		t.Errorf("Expected error parsing invalid failure policy, got %v", err)
+	}
+
+	if s := FailurePolicy(7).String(); s != "unknown (7)" {
+		t.Errorf("Incorrect string for invalid failure policy - expected:%v, got:%v", "unknown (7)", s)
+	}
+}
+
+// Returns the card number and door columns of the ACL table.
+func permissions(acl *ACL) [][]string {
+	rows := [][]string{}
+	for _, r := range acl.AsTable().Records {
+		rows = append(rows, append([]string{r[0]}, r[3:]...))
+	}
+
+	return rows
+}
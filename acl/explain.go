@@ -0,0 +1,163 @@
+package acl
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	core "github.com/uhppoted/uhppote-core/types"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// Explanation is a trace of the rules evaluated for a single member, with the changes made by
+// each rule to the card permissions and the resulting access for each door.
+type Explanation struct {
+	MemberID   uint32
+	Name       string
+	CardNumber uint32
+	StartDate  core.Date
+	EndDate    core.Date
+	Rules      []Step
+	Doors      []Decision
+}
+
+// Step is a rule that fired, in the order in which it was executed.
+type Step struct {
+	Cycle       uint64
+	Rule        string
+	Description string
+	Changes     []string
+}
+
+// Decision is the resulting access for a door, with the reason e.g. 'revoked'.
+type Decision struct {
+	Door       string
+	Permission string
+	Reason     string
+}
+
+// Evaluates the rules for a single member, tracing the rules that fired.
+func (rules *Rules) Explain(m types.Member, doors []string) (*Explanation, error) {
+	r := rules.newRecord(m, doors, true)
+	x := explainer{
+		record: &r,
+		doors:  doors,
+		steps:  []Step{},
+	}
+
+	if err := rules.eval(m, &r, &x); err != nil {
+		return nil, err
+	}
+
+	x.flush()
+
+	explanation := Explanation{
+		MemberID:   r.MemberID,
+		Name:       r.Name,
+		CardNumber: r.CardNumber,
+		StartDate:  r.StartDate,
+		EndDate:    r.EndDate,
+		Rules:      x.steps,
+		Doors:      []Decision{},
+	}
+
+	for _, door := range doors {
+		explanation.Doors = append(explanation.Doors, Decision{
+			Door:       door,
+			Permission: r.permission(door),
+			Reason:     r.reason(door),
+		})
+	}
+
+	return &explanation, nil
+}
+
+// explainer implements the tracer interface. The tracer is notified before a rule is executed
+// so the changes made by a rule are only known when the next rule (or grule cycle) starts or
+// the evaluation completes.
+type explainer struct {
+	record  *record
+	doors   []string
+	current *Step
+	before  record
+	steps   []Step
+}
+
+func (x *explainer) execute(cycle uint64, rule string, description string) {
+	x.flush()
+
+	x.current = &Step{
+		Cycle:       cycle,
+		Rule:        rule,
+		Description: description,
+	}
+
+	x.before = *x.record
+	x.before.Granted = maps.Clone(x.record.Granted)
+	x.before.Revoked = maps.Clone(x.record.Revoked)
+}
+
+func (x *explainer) flush() {
+	if x.current != nil {
+		x.current.Changes = diff(x.before, *x.record, x.doors)
+		x.steps = append(x.steps, *x.current)
+		x.current = nil
+	}
+}
+
+// Lists the changes between two versions of an ACL record.
+func diff(p, q record, doors []string) []string {
+	changes := []string{}
+
+	// ... use the door name rather than the normalised key if the door is in the door list
+	name := func(key string) string {
+		if key == "*" {
+			return "all doors"
+		}
+
+		for _, d := range doors {
+			if normalise(d) == key {
+				return d
+			}
+		}
+
+		return key
+	}
+
+	if p.CardNumber != q.CardNumber {
+		changes = append(changes, fmt.Sprintf("card number %v -> %v", p.CardNumber, q.CardNumber))
+	}
+
+	if p.PIN != q.PIN {
+		changes = append(changes, fmt.Sprintf("PIN %v -> %v", p.PIN, q.PIN))
+	}
+
+	if !p.StartDate.Equals(q.StartDate) {
+		changes = append(changes, fmt.Sprintf("start date %v -> %v", p.StartDate, q.StartDate))
+	}
+
+	if !p.EndDate.Equals(q.EndDate) {
+		changes = append(changes, fmt.Sprintf("end date %v -> %v", p.EndDate, q.EndDate))
+	}
+
+	for _, door := range slices.Sorted(maps.Keys(q.Granted)) {
+		if v, ok := p.Granted[door]; !ok || v != q.Granted[door] {
+			switch profile := q.Granted[door].(type) {
+			case int:
+				changes = append(changes, fmt.Sprintf("granted %v (time profile %v)", name(door), profile))
+
+			default:
+				changes = append(changes, fmt.Sprintf("granted %v", name(door)))
+			}
+		}
+	}
+
+	for _, door := range slices.Sorted(maps.Keys(q.Revoked)) {
+		if _, ok := p.Revoked[door]; !ok {
+			changes = append(changes, fmt.Sprintf("revoked %v", name(door)))
+		}
+	}
+
+	return changes
+}
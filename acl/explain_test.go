@@ -0,0 +1,80 @@
+package acl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	ruleset := `
+// *** GRULES ***
+rule StartDate "Sets the start date to the 'registered' field" salience 10 {
+     when
+		member.HasRegistered()
+	 then
+         permissions.SetStartDate(member.Registered);
+         Retract("StartDate");
+}
+
+rule Staff "Grants staff access" salience 5 {
+     when
+		member.Is("Staff")
+	 then
+         permissions.Grant("Great Hall", "Whomping Willow:29");
+         Retract("Staff");
+}
+
+rule Revoke "Revokes access to the Whomping Willow" salience 1 {
+     when
+		member.HasCardNumber(1000001)
+	 then
+         permissions.Revoke("Whomping Willow");
+         Retract("Revoke");
+}
+
+rule Students "Grants student access" {
+     when
+		member.Is("Student")
+	 then
+         permissions.Grant("Great Hall");
+         Retract("Students");
+}
+// *** END GRULES ***
+`
+
+	doors := []string{"Great Hall", "Whomping Willow", "Dungeon"}
+
+	expected := []Step{
+		Step{Cycle: 1, Rule: "StartDate", Description: "Sets the start date to the 'registered' field", Changes: []string{"start date " + startOfYear(nil).String() + " -> 1880-02-29"}},
+		Step{Cycle: 2, Rule: "Staff", Description: "Grants staff access", Changes: []string{"granted Great Hall", "granted Whomping Willow (time profile 29)"}},
+		Step{Cycle: 3, Rule: "Revoke", Description: "Revokes access to the Whomping Willow", Changes: []string{"revoked Whomping Willow"}},
+	}
+
+	decisions := []Decision{
+		Decision{Door: "Great Hall", Permission: "Y", Reason: "granted"},
+		Decision{Door: "Whomping Willow", Permission: "N", Reason: "revoked"},
+		Decision{Door: "Dungeon", Permission: "N", Reason: "not granted"},
+	}
+
+	rules, err := NewRules([]byte(ruleset), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	x, err := rules.Explain(dumbledore, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if x.CardNumber != 1000001 || x.Name != "Albus Dumbledore" {
+		t.Errorf("Incorrect member - expected:%v %v, got:%v %v", "Albus Dumbledore", 1000001, x.Name, x.CardNumber)
+	}
+
+	if !reflect.DeepEqual(x.Rules, expected) {
+		t.Errorf("Incorrect rules\n   expected:%+v\n   got:     %+v", expected, x.Rules)
+	}
+
+	if !reflect.DeepEqual(x.Doors, decisions) {
+		t.Errorf("Incorrect doors\n   expected:%+v\n   got:     %+v", decisions, x.Doors)
+	}
+}
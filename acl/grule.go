@@ -0,0 +1,145 @@
+package acl
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/hyperjumptech/grule-rule-engine/ast"
+	"github.com/hyperjumptech/grule-rule-engine/builder"
+	"github.com/hyperjumptech/grule-rule-engine/engine"
+	"github.com/hyperjumptech/grule-rule-engine/pkg"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// gruleset is the 'grule' rules engine implementation of the ruleset interface.
+type gruleset struct {
+	library   *ast.KnowledgeLibrary
+	instances sync.Pool
+}
+
+// Builds the 'grule' knowledge base from one or more rules files. The rule names must be unique
+// across all the rules files.
+func newGruleset(sources ...Source) (*gruleset, error) {
+	if len(sources) > 1 {
+		if err := checkRuleNames(sources); err != nil {
+			return nil, err
+		}
+	}
+
+	kb := ast.NewKnowledgeLibrary()
+	for _, source := range sources {
+		if err := builder.NewRuleBuilder(kb).BuildRuleFromResource("acl", "0.0.0", pkg.NewBytesResource(source.Ruleset)); err != nil {
+			if source.URI != "" {
+				return nil, fmt.Errorf("%v: %w", source.URI, err)
+			}
+
+			return nil, err
+		}
+	}
+
+	g := gruleset{
+		library: kb,
+	}
+
+	// ... precompile the knowledge base (also validates the knowledge base before use)
+	if instance, err := g.library.NewKnowledgeBaseInstance("acl", "0.0.0"); err != nil {
+		return nil, err
+	} else {
+		g.instances.Put(instance)
+	}
+
+	return &g, nil
+}
+
+func (g *gruleset) eval(m types.Member, r *record, evaluation Evaluation, trace tracer) error {
+	dctx := ast.NewDataContext()
+
+	if err := dctx.Add("member", &m); err != nil {
+		return err
+	}
+
+	if err := dctx.Add("permissions", r); err != nil {
+		return err
+	}
+
+	kb, err := g.instance()
+	if err != nil {
+		return err
+	}
+
+	defer g.instances.Put(kb)
+
+	enjin := engine.NewGruleEngine()
+
+	if trace != nil {
+		enjin.Listeners = []engine.GruleEngineListener{listener{trace}}
+	}
+
+	if evaluation.MaxCycles > 0 {
+		enjin.MaxCycle = evaluation.MaxCycles
+	}
+
+	if timeout := evaluation.Timeout; timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		return enjin.ExecuteWithContext(ctx, dctx, kb)
+	}
+
+	return enjin.Execute(dctx, kb)
+}
+
+// Builds each rules file into a separate knowledge library to check for duplicate rule names
+// across the rules files (the 'grule' builder only reports the duplicate rule name).
+func checkRuleNames(sources []Source) error {
+	names := map[string]string{}
+
+	for _, source := range sources {
+		kb := ast.NewKnowledgeLibrary()
+		if err := builder.NewRuleBuilder(kb).BuildRuleFromResource("acl", "0.0.0", pkg.NewBytesResource(source.Ruleset)); err != nil {
+			return fmt.Errorf("%v: %w", source.URI, err)
+		}
+
+		entries := kb.GetKnowledgeBase("acl", "0.0.0").RuleEntries
+		for _, name := range slices.Sorted(maps.Keys(entries)) {
+			if uri, ok := names[name]; ok {
+				return fmt.Errorf("duplicate rule '%v' in %v (already defined in %v)", name, source.URI, uri)
+			}
+
+			names[name] = source.URI
+		}
+	}
+
+	return nil
+}
+
+// Returns a knowledge base instance from the pool of precompiled instances, creating a new
+// instance if the pool is empty. The grule engine resets the working memory and retracted
+// rules on execution so an instance can be reused, but not concurrently.
+func (g *gruleset) instance() (*ast.KnowledgeBase, error) {
+	if kb, ok := g.instances.Get().(*ast.KnowledgeBase); ok && kb != nil {
+		return kb, nil
+	}
+
+	return g.library.NewKnowledgeBaseInstance("acl", "0.0.0")
+}
+
+// listener adapts a tracer to the grule GruleEngineListener interface.
+type listener struct {
+	tracer tracer
+}
+
+func (l listener) EvaluateRuleEntry(ctx context.Context, cycle uint64, entry *ast.RuleEntry, candidate bool) {
+}
+
+func (l listener) ExecuteRuleEntry(ctx context.Context, cycle uint64, entry *ast.RuleEntry) {
+	l.tracer.execute(cycle, entry.RuleName, entry.RuleDescription)
+}
+
+func (l listener) BeginCycle(ctx context.Context, cycle uint64) {
+	l.tracer.flush()
+}
@@ -0,0 +1,345 @@
+package acl
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hyperjumptech/grule-rule-engine/ast"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// Vocabulary is the list of doors, door zones, controllers, member groups and membership levels
+// that are valid arguments for the rules.
+type Vocabulary struct {
+	Doors       []string
+	Zones       Zones
+	Controllers Controllers
+	Groups      []types.Group
+	Memberships []types.Membership
+}
+
+// Lint checks the rules against the vocabulary and returns a list of warnings for:
+//   - doors passed to Grant or Revoke that are not in the door list
+//   - zones and controllers passed to Grant or Revoke that are not defined
+//   - door patterns passed to Grant or Revoke that are invalid or do not match any door
+//   - groups passed to HasGroup that are not member groups
+//   - membership levels passed to Is that are not account membership levels
+//   - unknown member and permissions functions (and variables other than 'member' and 'permissions')
+//   - rules that do not Retract themselves
+//
+// and zones that include doors that are not in the door list.
+func (rules *Rules) Lint(v Vocabulary) []string {
+	warnings := []string{}
+
+	for _, zone := range slices.Sorted(maps.Keys(v.Zones)) {
+		for _, door := range v.Zones[zone] {
+			if !slices.ContainsFunc(v.Doors, func(d string) bool { return normalise(d) == normalise(door) }) {
+				warnings = append(warnings, fmt.Sprintf("zone %v: unknown door '%v'", zone, door))
+			}
+		}
+	}
+
+	return append(warnings, rules.ruleset.lint(v, rules.schedules)...)
+}
+
+func (g *gruleset) lint(v Vocabulary, schedules Schedules) []string {
+	warnings := []string{}
+
+	kb := g.library.GetKnowledgeBase("acl", "0.0.0")
+	if kb == nil {
+		return []string{"missing 'acl' knowledge base"}
+	}
+
+	names := []string{}
+	for name := range kb.RuleEntries {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		l := linter{
+			rule:       kb.RuleEntries[name],
+			vocabulary: v,
+			schedules:  schedules,
+			warnings:   []string{},
+		}
+
+		l.lint()
+
+		for _, w := range l.warnings {
+			warnings = append(warnings, fmt.Sprintf("rule %v: %v", name, w))
+		}
+	}
+
+	return warnings
+}
+
+type linter struct {
+	rule       *ast.RuleEntry
+	vocabulary Vocabulary
+	schedules  Schedules
+	retracted  bool
+	warnings   []string
+}
+
+func (l *linter) lint() {
+	if l.rule.WhenScope != nil {
+		l.expression(l.rule.WhenScope.Expression)
+	}
+
+	if l.rule.ThenScope != nil && l.rule.ThenScope.ThenExpressionList != nil {
+		for _, e := range l.rule.ThenScope.ThenExpressionList.ThenExpressions {
+			if e.Assignment != nil {
+				l.expression(e.Assignment.Expression)
+			}
+
+			l.atom(e.ExpressionAtom)
+		}
+	}
+
+	if !l.retracted {
+		l.warnf("missing Retract(\"%v\")", l.rule.RuleName)
+	}
+}
+
+func (l *linter) expression(e *ast.Expression) {
+	if e != nil {
+		l.expression(e.LeftExpression)
+		l.expression(e.RightExpression)
+		l.expression(e.SingleExpression)
+		l.atom(e.ExpressionAtom)
+	}
+}
+
+func (l *linter) atom(a *ast.ExpressionAtom) {
+	if a == nil {
+		return
+	}
+
+	l.atom(a.ExpressionAtom)
+
+	if a.ArrayMapSelector != nil {
+		l.expression(a.ArrayMapSelector.Expression)
+	}
+
+	if a.FunctionCall != nil && a.FunctionCall.ArgumentList != nil {
+		for _, arg := range a.FunctionCall.ArgumentList.Arguments {
+			l.expression(arg)
+		}
+	}
+
+	// ... member.Field
+	if a.Variable != nil && a.Variable.Variable != nil {
+		if receiver := a.Variable.Variable.Name; receiver == "member" {
+			if !hasField(reflect.TypeFor[types.Member](), a.Variable.Name) {
+				l.warnf("unknown member field '%v'", a.Variable.Name)
+			}
+		}
+	}
+
+	if a.FunctionCall == nil {
+		return
+	}
+
+	function := a.FunctionCall.FunctionName
+	args := arguments(a.FunctionCall)
+
+	// ... built-in functions
+	if a.ExpressionAtom == nil {
+		if function == "Retract" {
+			l.retracted = true
+			if len(args) == 1 && args[0] != l.rule.RuleName {
+				l.warnf("Retract(\"%v\") does not match rule name", args[0])
+			}
+		}
+
+		return
+	}
+
+	// ... member and permissions functions
+	if a.ExpressionAtom.Variable == nil {
+		return
+	}
+
+	switch a.ExpressionAtom.Variable.Name {
+	case "member":
+		if _, ok := reflect.TypeFor[*types.Member]().MethodByName(function); !ok {
+			l.warnf("unknown member function '%v'", function)
+		}
+
+		switch function {
+		case "HasGroup":
+			l.groups(args, same)
+
+		case "Is":
+			l.memberships(args, same)
+		}
+
+	case "permissions":
+		if _, ok := reflect.TypeFor[*record]().MethodByName(function); !ok {
+			l.warnf("unknown permissions function '%v'", function)
+		}
+
+		switch function {
+		case "Grant":
+			l.doors(l.granted(args))
+
+		case "Revoke":
+			l.doors(args)
+		}
+
+	default:
+		l.warnf("unknown variable '%v'", a.ExpressionAtom.Variable.Name)
+	}
+}
+
+// Returns the doors for Grant, using the same rules as record.Grant to distinguish between
+// doors and time profiles/schedules.
+func (l *linter) granted(args []any) []any {
+	if len(args) == 2 {
+		if door, ok := args[0].(string); ok {
+			switch profile := args[1].(type) {
+			case int64:
+				return []any{door}
+
+			case string:
+				if _, ok := l.schedules.lookup(profile); ok {
+					return []any{door}
+				}
+			}
+		}
+	}
+
+	doors := []any{}
+	for _, arg := range args {
+		if d, ok := arg.(string); ok {
+			door, _ := parseGrant(d, l.schedules)
+			doors = append(doors, door)
+		}
+	}
+
+	return doors
+}
+
+func (l *linter) doors(args []any) {
+	for _, arg := range args {
+		if door, ok := arg.(string); ok && door != "*" {
+			prefix, name := splitSelector(door)
+			door = strings.TrimSpace(door)
+
+			switch {
+			case prefix == "zone:":
+				if !l.vocabulary.Zones.defined(name) {
+					l.warnf("unknown zone '%v'", name)
+				}
+
+			case prefix == "controller:":
+				if id, err := strconv.ParseUint(name, 10, 32); err != nil {
+					l.warnf("invalid controller '%v'", name)
+				} else if _, ok := l.vocabulary.Controllers[uint32(id)]; !ok {
+					l.warnf("unknown controller %v", name)
+				}
+
+			case isRegex(door):
+				if _, err := regex(door); err != nil {
+					l.warnf("invalid door pattern '%v' (%v)", door, err)
+				} else if !slices.ContainsFunc(l.vocabulary.Doors, func(d string) bool { return matches(door, d, nil, nil) }) {
+					l.warnf("door pattern '%v' does not match any door", door)
+				}
+
+			case isGlob(door):
+				if _, err := path.Match(key(door), ""); err != nil {
+					l.warnf("invalid door pattern '%v' (%v)", door, err)
+				} else if !slices.ContainsFunc(l.vocabulary.Doors, func(d string) bool { return matches(key(door), d, nil, nil) }) {
+					l.warnf("door pattern '%v' does not match any door", door)
+				}
+
+			default:
+				if !slices.ContainsFunc(l.vocabulary.Doors, func(d string) bool { return normalise(d) == normalise(door) }) {
+					l.warnf("unknown door '%v'", door)
+				}
+			}
+		}
+	}
+}
+
+func (l *linter) groups(args []any, same func(p, q string) bool) {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			if !slices.ContainsFunc(l.vocabulary.Groups, func(g types.Group) bool { return same(g.Name, v) }) {
+				l.warnf("unknown group '%v'", v)
+			}
+
+		case int64:
+			if !slices.ContainsFunc(l.vocabulary.Groups, func(g types.Group) bool { return int64(g.ID) == v }) {
+				l.warnf("unknown group ID %v", v)
+			}
+		}
+	}
+}
+
+func (l *linter) memberships(args []any, same func(p, q string) bool) {
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			if !slices.ContainsFunc(l.vocabulary.Memberships, func(m types.Membership) bool { return same(m.Name, v) }) {
+				l.warnf("unknown membership level '%v'", v)
+			}
+
+		case int64:
+			if !slices.ContainsFunc(l.vocabulary.Memberships, func(m types.Membership) bool { return int64(m.ID) == v }) {
+				l.warnf("unknown membership level ID %v", v)
+			}
+		}
+	}
+}
+
+func (l *linter) warnf(format string, args ...any) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+// Returns the constant arguments for a function call (non-constant arguments are nil).
+func arguments(f *ast.FunctionCall) []any {
+	args := []any{}
+
+	if f.ArgumentList != nil {
+		for _, arg := range f.ArgumentList.Arguments {
+			if arg.ExpressionAtom != nil && arg.ExpressionAtom.Constant != nil && arg.ExpressionAtom.Constant.Value.IsValid() {
+				args = append(args, arg.ExpressionAtom.Constant.Value.Interface())
+			} else {
+				args = append(args, nil)
+			}
+		}
+	}
+
+	return args
+}
+
+func hasField(t reflect.Type, name string) bool {
+	f, ok := t.FieldByName(name)
+
+	return ok && f.IsExported()
+}
+
+// Compares group and membership level names the same way as the types.Member functions
+// i.e. ignoring case and any non-alphanumeric characters.
+func same(p, q string) bool {
+	re := regexp.MustCompile(`[^a-z0-9]`)
+
+	return re.ReplaceAllString(strings.ToLower(p), "") == re.ReplaceAllString(strings.ToLower(q), "")
+}
+
+// Compares group and membership level names exactly, for the CEL member.groups and
+// member.membership fields.
+func exact(p, q string) bool {
+	return p == q
+}
@@ -0,0 +1,88 @@
+package acl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+func TestLint(t *testing.T) {
+	ruleset := `
+// *** GRULES ***
+rule StartDate "Sets the start date to the 'registered' field" {
+     when
+		member.HasRegistered() && member.Registerd != nil
+	 then
+         permissions.SetStartDate(member.Registered);
+         Retract("StartDate");
+}
+
+rule Staff "Grants staff access" {
+     when
+		member.Is("Staff") || member.Is(12345) || member.HasGroup("Teachers") || member.HasGroup(7)
+	 then
+         permissions.Grant("Great Hall", "Whomping Wilow:29", "Dungeon:weekday-evenings");
+         permissions.Grant("Kitchen", 30);
+         permissions.Revoke("Gryfindor", "*");
+         Retract("Staff");
+}
+
+rule Students "Grants student access" {
+     when
+		member.Is("Student") && member.IsStudent()
+	 then
+         permissions.Grant("Great Hall");
+         permissions.Allow("Great Hall");
+         record.Grant("Great Hall");
+         Retract("Student");
+}
+
+rule Alumni "Grants alumni access" {
+     when
+		member.Is("Alumni") && member.HasGroup("Gryffindor")
+	 then
+         permissions.Grant("Great Hall");
+}
+// *** END GRULES ***
+`
+
+	vocabulary := Vocabulary{
+		Doors: []string{"Great Hall", "Whomping Willow", "Dungeon", "Gryffindor"},
+		Groups: []types.Group{
+			types.Group{ID: 1, Name: "Teachers"},
+			types.Group{ID: 2, Name: "Gryffindor"},
+		},
+		Memberships: []types.Membership{
+			types.Membership{ID: 12345, Name: "Staff"},
+			types.Membership{ID: 12346, Name: "Student"},
+		},
+	}
+
+	expected := []string{
+		"rule Alumni: unknown membership level 'Alumni'",
+		`rule Alumni: missing Retract("Alumni")`,
+		"rule Staff: unknown group ID 7",
+		"rule Staff: unknown door 'Whomping Wilow'",
+		"rule Staff: unknown door 'Kitchen'",
+		"rule Staff: unknown door 'Gryfindor'",
+		"rule StartDate: unknown member field 'Registerd'",
+		"rule Students: unknown member function 'IsStudent'",
+		"rule Students: unknown permissions function 'Allow'",
+		"rule Students: unknown variable 'record'",
+		`rule Students: Retract("Student") does not match rule name`,
+	}
+
+	rules, err := NewRules([]byte(ruleset), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetSchedules(Schedules{Schedule{Name: "weekday-evenings"}})
+
+	warnings := rules.Lint(vocabulary)
+
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Incorrect lint warnings\n   expected:%q\n   got:     %q", expected, warnings)
+	}
+}
@@ -0,0 +1,74 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/hyperjumptech/grule-rule-engine/logger"
+)
+
+// Source is a 'grule' rules file or YAML/JSON policy file to be merged into a single ruleset.
+type Source struct {
+	URI     string
+	Ruleset []byte
+}
+
+// MergeRules builds a single ruleset from multiple 'grule' rules files and/or YAML/JSON policy
+// files e.g. a common base ruleset and per-site overrides. The rules from all the files are
+// evaluated together in salience order and the rule names must be unique across all the files.
+//
+// A single source is equivalent to ParseRules (CEL rules files cannot be merged).
+func MergeRules(sources []Source, debug bool) (*Rules, error) {
+	switch len(sources) {
+	case 0:
+		return nil, fmt.Errorf("no rules files")
+
+	case 1:
+		return ParseRules(sources[0].URI, sources[0].Ruleset, debug)
+	}
+
+	if debug {
+		logger.SetLogLevel(logger.TraceLevel)
+	} else {
+		logger.SetLogLevel(logger.ErrorLevel)
+	}
+
+	rulesets := []Source{}
+	hash := sha256.New()
+
+	for _, source := range sources {
+		ruleset := source.Ruleset
+
+		switch Format(source.URI) {
+		case ".yaml", ".yml", ".json":
+			if grules, err := translatePolicy(ruleset); err != nil {
+				return nil, fmt.Errorf("%v: %w", source.URI, err)
+			} else {
+				ruleset = grules
+			}
+
+		case ".cel":
+			return nil, fmt.Errorf("%v: CEL rules files cannot be merged with other rules files", source.URI)
+
+		default:
+			if err := checkMarkers(ruleset); err != nil {
+				return nil, fmt.Errorf("%v: %w", source.URI, err)
+			}
+		}
+
+		hash.Write(source.Ruleset)
+		rulesets = append(rulesets, Source{URI: source.URI, Ruleset: ruleset})
+	}
+
+	g, err := newGruleset(rulesets...)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := Rules{
+		hash:    hash.Sum(nil),
+		ruleset: g,
+	}
+
+	return &rules, nil
+}
@@ -0,0 +1,87 @@
+package acl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var siteOverride = `
+// *** GRULES ***
+rule HogsmeadeClosed "Revokes Hogsmeade access for the site" salience -20 {
+     when
+		member.HasCardNumber(6000002)
+	 then
+         permissions.Revoke("Hogsmeade");
+         Retract("HogsmeadeClosed");
+}
+// *** END GRULES ***
+`
+
+func TestMergeRules(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, admin, harry, hermione, voldemort},
+	}
+
+	doors := []string{"Great Hall", "Hogsmeade", "Whomping Willow"}
+
+	expected := [][]string{
+		{"1000001", "Y", "Y", "Y"},
+		{"2000001", "N", "N", "N"},
+		{"6000001", "Y", "Y", "29"},
+		{"6000002", "N", "N", "N"},
+	}
+
+	sources := []Source{
+		{URI: "common.yaml", Ruleset: []byte(policyYAML)},
+		{URI: "site.grl", Ruleset: []byte(siteOverride)},
+	}
+
+	rules, err := MergeRules(sources, false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	acl, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if rows := permissions(acl); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, rows)
+	}
+}
+
+func TestMergeRulesWithInvalidSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  []Source
+		expected string
+	}{
+		{
+			"duplicate rule",
+			[]Source{{URI: "common.grl", Ruleset: []byte(grules)}, {URI: "site.grl", Ruleset: []byte(grules)}},
+			"duplicate rule 'EndDate' in site.grl (already defined in common.grl)",
+		},
+		{
+			"CEL rules",
+			[]Source{{URI: "common.grl", Ruleset: []byte(grules)}, {URI: "site.cel", Ruleset: []byte(celrules)}},
+			"site.cel: CEL rules files cannot be merged with other rules files",
+		},
+		{
+			"missing markers",
+			[]Source{{URI: "common.grl", Ruleset: []byte(grules)}, {URI: "site.grl", Ruleset: []byte("rule X {}")}},
+			"site.grl: invalid 'grules' file - missing start/end markers",
+		},
+	}
+
+	for _, test := range tests {
+		if _, err := MergeRules(test.sources, false); err == nil {
+			t.Errorf("%v: expected error, got %v", test.name, err)
+		} else if !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("%v: incorrect error\n   expected:%v\n   got:     %v", test.name, test.expected, err)
+		}
+	}
+}
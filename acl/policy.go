@@ -0,0 +1,315 @@
+package acl
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	core "github.com/uhppoted/uhppote-core/types"
+)
+
+// AccessPolicy is a declarative alternative to a 'grule' rules file for the common case of mapping
+// member groups, membership levels and fields to door grants, e.g.:
+//
+//	rules:
+//	  - name: Staff
+//	    when:
+//	      membership: [ Staff ]
+//	    grant: [ "*" ]
+//
+//	  - name: Suspended
+//	    salience: -10
+//	    when:
+//	      suspended: true
+//	    revoke: [ "*" ]
+//
+// A policy is translated to the equivalent 'grule' rules so that it behaves identically to
+// a rules file (including explain and lint-rules). The policy may be formatted as either
+// YAML or JSON.
+type AccessPolicy struct {
+	Rules []PolicyRule `yaml:"rules"`
+}
+
+// PolicyRule grants and revokes access to doors and sets the card start and end dates for
+// members that match all the conditions. A rule without conditions applies to all members.
+type PolicyRule struct {
+	Name          string    `yaml:"name"`
+	Description   string    `yaml:"description"`
+	Salience      int       `yaml:"salience"`
+	When          Condition `yaml:"when"`
+	Grant         []string  `yaml:"grant"`
+	Revoke        []string  `yaml:"revoke"`
+	StartDate     string    `yaml:"start-date"`
+	EndDate       string    `yaml:"end-date"`
+	ExtendEndDate int       `yaml:"extend-end-date"`
+}
+
+// Condition matches a member if the member is in any of the groups, has any of the membership
+// levels and has all of the field values (conditions that are not specified always match).
+type Condition struct {
+	Groups     []string          `yaml:"groups"`
+	Membership []string          `yaml:"membership"`
+	Fields     map[string]string `yaml:"fields"`
+	Active     *bool             `yaml:"active"`
+	Suspended  *bool             `yaml:"suspended"`
+}
+
+// NewPolicy parses a YAML or JSON policy and translates it to the equivalent 'grule' rules.
+func NewPolicy(policy []byte, debug bool) (*Rules, error) {
+	ruleset, err := translatePolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRules(ruleset, debug)
+}
+
+// Parses a YAML or JSON policy and returns the equivalent 'grule' rules.
+func translatePolicy(policy []byte) ([]byte, error) {
+	var p AccessPolicy
+
+	decoder := yaml.NewDecoder(bytes.NewReader(policy))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid policy (%v)", err)
+	}
+
+	return p.grules()
+}
+
+func (p AccessPolicy) grules() ([]byte, error) {
+	var b bytes.Buffer
+
+	names := map[string]bool{}
+
+	fmt.Fprintln(&b, "// *** GRULES ***")
+
+	for i, r := range p.Rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("Rule%v", i+1)
+		}
+
+		if !regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`).MatchString(name) {
+			return nil, fmt.Errorf("invalid policy rule name '%v'", name)
+		} else if names[name] {
+			return nil, fmt.Errorf("duplicate policy rule name '%v'", name)
+		}
+
+		names[name] = true
+
+		variants, err := r.variants(name)
+		if err != nil {
+			return nil, fmt.Errorf("policy rule %v: %v", name, err)
+		}
+
+		for _, v := range variants {
+			if v.name != name {
+				if names[v.name] {
+					return nil, fmt.Errorf("duplicate policy rule name '%v'", v.name)
+				}
+
+				names[v.name] = true
+			}
+
+			fmt.Fprintln(&b)
+			fmt.Fprintf(&b, "rule %v %v salience %v {\n", v.name, quote(r.Description), r.Salience)
+			fmt.Fprintf(&b, "    when\n")
+			fmt.Fprintf(&b, "        %v\n", v.when)
+			fmt.Fprintf(&b, "    then\n")
+			for _, action := range v.then {
+				fmt.Fprintf(&b, "        %v;\n", action)
+			}
+			fmt.Fprintf(&b, "        Retract(%v);\n", quote(v.name))
+			fmt.Fprintln(&b, "}")
+		}
+	}
+
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "// *** END GRULES ***")
+
+	return b.Bytes(), nil
+}
+
+func (c Condition) expression() string {
+	conditions := []string{}
+
+	anyOf := func(function string, values []string) {
+		list := []string{}
+		for _, v := range values {
+			list = append(list, fmt.Sprintf("member.%v(%v)", function, quote(v)))
+		}
+
+		if len(list) == 1 {
+			conditions = append(conditions, list[0])
+		} else if len(list) > 1 {
+			conditions = append(conditions, "("+strings.Join(list, " || ")+")")
+		}
+	}
+
+	anyOf("HasGroup", c.Groups)
+	anyOf("Is", c.Membership)
+
+	fields := slices.Sorted(maps.Keys(c.Fields))
+	for _, k := range fields {
+		conditions = append(conditions, fmt.Sprintf("member.Get(%v) == %v", quote(k), quote(c.Fields[k])))
+	}
+
+	if c.Active != nil {
+		if *c.Active {
+			conditions = append(conditions, "member.IsActive()")
+		} else {
+			conditions = append(conditions, "!member.IsActive()")
+		}
+	}
+
+	if c.Suspended != nil {
+		if *c.Suspended {
+			conditions = append(conditions, "member.IsSuspended()")
+		} else {
+			conditions = append(conditions, "!member.IsSuspended()")
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "true"
+	}
+
+	return strings.Join(conditions, " && ")
+}
+
+// variant is a 'grule' rule generated from a policy rule.
+type variant struct {
+	name string
+	when string
+	then []string
+}
+
+// Returns the 'grule' rules for a policy rule. A 'registered' start date or 'expires' end date
+// is only set for members that have the date, so a policy rule with either is translated to
+// mutually exclusive rules for members with and without the date (e.g. Staff and
+// Staff_NoRegistered) that otherwise apply the same actions in the same order.
+func (r PolicyRule) variants(name string) ([]variant, error) {
+	type option struct {
+		start bool
+		end   bool
+	}
+
+	variants := []struct {
+		name       string
+		conditions []string
+		option
+	}{
+		{name, []string{}, option{true, true}},
+	}
+
+	split := func(field string, function string, without func(*option)) {
+		list := variants[:0:0]
+		for _, v := range variants {
+			with := v
+			with.conditions = append(slices.Clone(v.conditions), fmt.Sprintf("member.%v()", function))
+
+			other := v
+			other.name = v.name + "_No" + field
+			other.conditions = append(slices.Clone(v.conditions), fmt.Sprintf("!member.%v()", function))
+			without(&other.option)
+
+			list = append(list, with, other)
+		}
+
+		variants = list
+	}
+
+	if strings.EqualFold(r.StartDate, "Registered") {
+		split("Registered", "HasRegistered", func(o *option) { o.start = false })
+	}
+
+	if strings.EqualFold(r.EndDate, "Expires") {
+		split("Expires", "HasExpires", func(o *option) { o.end = false })
+	}
+
+	list := []variant{}
+	for _, v := range variants {
+		then, err := r.actions(v.start, v.end)
+		if err != nil {
+			return nil, err
+		}
+
+		when := r.When.expression()
+		if len(v.conditions) > 0 {
+			if when == "true" {
+				when = strings.Join(v.conditions, " && ")
+			} else {
+				when = strings.Join(append([]string{when}, v.conditions...), " && ")
+			}
+		}
+
+		list = append(list, variant{
+			name: v.name,
+			when: when,
+			then: then,
+		})
+	}
+
+	return list, nil
+}
+
+// Returns the 'grule' actions for a policy rule, optionally omitting the 'registered' start date
+// and 'expires' end date (for members without the date).
+func (r PolicyRule) actions(withStart, withEnd bool) ([]string, error) {
+	actions := []string{}
+
+	date := func(function string, v string, field string, include bool) error {
+		switch {
+		case v == "":
+
+		case strings.EqualFold(v, field):
+			if include {
+				actions = append(actions, fmt.Sprintf("permissions.%v(member.%v)", function, field))
+			}
+
+		default:
+			if _, err := core.ParseDate(v); err != nil {
+				return fmt.Errorf("invalid date '%v'", v)
+			}
+
+			actions = append(actions, fmt.Sprintf("permissions.%v(%v)", function, quote(v)))
+		}
+
+		return nil
+	}
+
+	for _, door := range r.Grant {
+		actions = append(actions, fmt.Sprintf("permissions.Grant(%v)", quote(door)))
+	}
+
+	for _, door := range r.Revoke {
+		actions = append(actions, fmt.Sprintf("permissions.Revoke(%v)", quote(door)))
+	}
+
+	if err := date("SetStartDate", r.StartDate, "Registered", withStart); err != nil {
+		return nil, err
+	}
+
+	if err := date("SetEndDate", r.EndDate, "Expires", withEnd); err != nil {
+		return nil, err
+	}
+
+	if r.ExtendEndDate != 0 {
+		actions = append(actions, fmt.Sprintf("permissions.ExtendEndDate(%v)", r.ExtendEndDate))
+	}
+
+	return actions, nil
+}
+
+// Returns a string as a 'grule' string literal.
+func quote(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,170 @@
+package acl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var policyYAML = `
+rules:
+  - name: Staff
+    description: Grants staff access to all doors
+    when:
+      membership: [ Staff ]
+    grant: [ "*" ]
+    start-date: registered
+
+  - name: Students
+    when:
+      membership: [ Student, Stduent ]
+      active: true
+    grant: [ Great Hall, "Whomping Willow:29" ]
+    end-date: expires
+
+  - name: Everyone
+    grant: [ "Hogsmeade" ]
+
+  - name: Suspended
+    salience: -10
+    when:
+      suspended: true
+    revoke: [ "*" ]
+`
+
+var policyJSON = `{
+  "rules": [
+    { "name": "Staff", "when": { "membership": [ "Staff" ] }, "grant": [ "*" ], "start-date": "registered" },
+    { "name": "Students", "when": { "membership": [ "Student", "Stduent" ], "active": true }, "grant": [ "Great Hall", "Whomping Willow:29" ], "end-date": "expires" },
+    { "name": "Everyone", "grant": [ "Hogsmeade" ] },
+    { "name": "Suspended", "salience": -10, "when": { "suspended": true }, "revoke": [ "*" ] }
+  ]
+}`
+
+func TestPolicy(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, admin, harry, hermione, voldemort},
+	}
+
+	doors := []string{"Great Hall", "Hogsmeade", "Whomping Willow"}
+
+	expected := [][]string{
+		{"1000001", "Y", "Y", "Y"},
+		{"2000001", "N", "N", "N"},
+		{"6000001", "Y", "Y", "29"},
+		{"6000002", "N", "Y", "N"},
+	}
+
+	for _, uri := range []string{"wild-apricot.yaml", "file://wild-apricot.json?v=1"} {
+		policy := policyYAML
+		if Format(uri) == ".json" {
+			policy = policyJSON
+		}
+
+		rules, err := ParseRules(uri, []byte(policy), false)
+		if err != nil {
+			t.Fatalf("%v: unexpected error (%v)", uri, err)
+		}
+
+		acl, err := rules.MakeACL(members, doors)
+		if err != nil {
+			t.Fatalf("%v: unexpected error (%v)", uri, err)
+		}
+
+		if rows := permissions(acl); !reflect.DeepEqual(rows, expected) {
+			t.Errorf("%v: incorrect ACL\n   expected:%v\n   got:     %v", uri, expected, rows)
+		}
+
+		table := acl.AsTable()
+		if start := table.Records[0][1]; start != "1880-02-29" {
+			t.Errorf("%v: incorrect start date - expected:%v, got:%v", uri, "1880-02-29", start)
+		}
+
+		if end := table.Records[2][2]; end != "2021-06-30" {
+			t.Errorf("%v: incorrect end date - expected:%v, got:%v", uri, "2021-06-30", end)
+		}
+	}
+}
+
+func TestPolicyWithQuotedStrings(t *testing.T) {
+	policy := `
+rules:
+  - name: Quoted
+    when:
+      fields: { "Nick\"name": "Albus \"Dumbledore\"" }
+    grant: [ "Great \"Hall\"" ]
+`
+
+	if _, err := NewPolicy([]byte(policy), false); err != nil {
+		t.Errorf("Unexpected error (%v)", err)
+	}
+}
+
+func TestPolicyWithInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":  "rules:\n  - name: Staff\n    grants: [ Great Hall ]\n",
+		"invalid name":   "rules:\n  - name: Staff Room\n    grant: [ Great Hall ]\n",
+		"duplicate name": "rules:\n  - name: Staff\n  - name: Staff\n",
+		"invalid date":   "rules:\n  - name: Staff\n    end-date: tomorrow\n",
+	}
+
+	for k, policy := range tests {
+		if _, err := NewPolicy([]byte(policy), false); err == nil {
+			t.Errorf("%v: expected error, got %v", k, err)
+		}
+	}
+}
+
+func TestPolicyWithoutDates(t *testing.T) {
+	snape := types.Member{
+		Name:       "Severus Snape",
+		CardNumber: &C1000001,
+		Active:     true,
+		Membership: types.Membership{
+			ID:   25342355,
+			Name: "Staff",
+		},
+	}
+
+	ron := types.Member{
+		Name:       "Ron Weasley",
+		CardNumber: &C6000001,
+		Active:     true,
+		Membership: types.Membership{
+			ID:   545454,
+			Name: "Student",
+		},
+	}
+
+	members := types.Members{
+		Members: []types.Member{snape, ron},
+	}
+
+	doors := []string{"Great Hall", "Hogsmeade", "Whomping Willow"}
+
+	expected := [][]string{
+		{"1000001", "Y", "Y", "Y"},
+		{"6000001", "Y", "Y", "29"},
+	}
+
+	rules, err := NewPolicy([]byte(policyYAML), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	acl, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if rows := permissions(acl); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, rows)
+	}
+
+	for _, record := range acl.AsTable().Records {
+		if start, end := record[1], record[2]; start == "" || end == "" {
+			t.Errorf("%v: missing start/end date - from:'%v', to:'%v'", record[0], start, end)
+		}
+	}
+}
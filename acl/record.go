@@ -1,21 +1,30 @@
 package acl
 
 import (
-	"regexp"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	core "github.com/uhppoted/uhppote-core/types"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/log"
 )
 
 type record struct {
-	Name       string
-	CardNumber uint32
-	PIN        uint32
-	StartDate  core.Date
-	EndDate    core.Date
-	Granted    map[string]any
-	Revoked    map[string]struct{}
+	MemberID    uint32
+	Name        string
+	CardNumber  uint32
+	PIN         uint32
+	StartDate   core.Date
+	EndDate     core.Date
+	Granted     map[string]any
+	Revoked     map[string]struct{}
+	doors       []string
+	schedules   Schedules
+	zones       Zones
+	controllers Controllers
 }
 
 func (r *record) SetCardNumber(card any) {
@@ -80,18 +89,55 @@ func (r *record) SetEndDate(t any) {
 	}
 }
 
+// Extends the card end date by the given number of days e.g. to allow a grace period
+// after the membership renewal date.
+func (r *record) ExtendEndDate(days any) {
+	if r != nil && !r.EndDate.IsZero() {
+		switch v := days.(type) {
+		case int:
+			r.EndDate = plusDays(r.EndDate, v)
+
+		case int64:
+			r.EndDate = plusDays(r.EndDate, int(v))
+		}
+	}
+}
+
 func (r *record) Grant(permissions ...any) {
 	if r != nil {
-		// parse Grant(door, profile)
+		// parse Grant(door, profile) and Grant(door, schedule)
 		if len(permissions) == 2 {
 			if door, ok := permissions[0].(string); ok {
 				switch profile := permissions[1].(type) {
 				case int:
-					r.Granted[normalise(door)] = profile
+					r.Granted[key(door)] = profile
 					return
 
 				case int64:
-					r.Granted[normalise(door)] = int(profile)
+					r.Granted[key(door)] = int(profile)
+					return
+
+				case string:
+					if id, ok := r.schedules.lookup(profile); ok {
+						r.Granted[key(door)] = id
+						return
+					}
+
+					// ... an unknown schedule is an error unless the second argument is a door (otherwise a
+					//     misspelt schedule would grant unrestricted access to the door)
+					if !r.known(profile) {
+						log.Warnf("%v: Grant(%q, %q) - unknown door or schedule", r.Name, door, profile)
+						return
+					}
+
+					// ... grant the known doors (unknown doors are reported by lint-rules)
+					for _, d := range []string{door, profile} {
+						if r.known(d) {
+							door, v := parseGrant(d, r.schedules)
+							r.Granted[key(door)] = v
+						}
+					}
+
 					return
 				}
 			}
@@ -100,22 +146,149 @@ func (r *record) Grant(permissions ...any) {
 		// parse Grant(door...)
 		for _, p := range permissions {
 			if d, ok := p.(string); ok {
-				if match := regexp.MustCompile(`(\S.*?):([0-9]+)`).FindStringSubmatch(d); match != nil {
-					door := normalise(match[1])
-					profile, _ := strconv.Atoi(match[2])
-					r.Granted[door] = profile
-				} else {
-					r.Granted[normalise(d)] = true
-				}
+				door, v := parseGrant(d, r.schedules)
+				r.Granted[key(door)] = v
+			}
+		}
+	}
+}
+
+// Returns true if a Grant argument is a door, zone, controller, door pattern or '*', with an
+// optional time profile or schedule. Doors are matched against the doors for the ACL and the
+// doors on all the configured controllers (the ACL doors are only the doors for the site).
+func (r record) known(arg string) bool {
+	door, _ := parseGrant(arg, r.schedules)
+	prefix, name := splitSelector(door)
+	name = strings.TrimSpace(name)
+
+	switch {
+	case prefix == "zone:":
+		return r.zones.defined(name)
+
+	case prefix == "controller:":
+		if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+			_, ok := r.controllers[uint32(id)]
+			return ok
+		}
+
+		return false
+
+	case name == "*" || isRegex(name) || isGlob(name):
+		return true
+
+	default:
+		same := func(d string) bool { return normalise(d) == normalise(name) }
+
+		if slices.ContainsFunc(r.doors, same) {
+			return true
+		}
+
+		for _, doors := range r.controllers {
+			if slices.ContainsFunc(doors, same) {
+				return true
 			}
 		}
+
+		return false
 	}
 }
 
 func (r *record) Revoke(door ...string) {
 	if r != nil {
 		for _, d := range door {
-			r.Revoked[normalise(d)] = struct{}{}
+			r.Revoked[key(d)] = struct{}{}
 		}
 	}
 }
+
+// Returns true if a Granted or Revoked key matches a door, expanding zones, controllers and
+// door patterns.
+func (r record) matches(key string, door string) bool {
+	return matches(key, door, r.zones, r.controllers)
+}
+
+// Returns the ACL table entry for a door i.e. "Y" if access has been granted, the time profile
+// ID if access has been granted with a time profile and "N" if access has not been granted or
+// has been revoked. A time profile granted for the door takes precedence over a time profile
+// granted for a zone, controller or door pattern that includes the door.
+func (r record) permission(door string) string {
+	granted := false
+	revoked := false
+	profile := -1
+	zoned := -1
+	d := normalise(door)
+
+	if _, ok := r.Granted["*"]; ok {
+		granted = true
+	}
+
+	for k, v := range r.Granted {
+		if r.matches(k, door) {
+			switch vv := v.(type) {
+			case bool:
+				if vv {
+					granted = true
+				}
+
+			case int:
+				if vv >= 2 && vv <= 254 {
+					granted = true
+					if normalise(k) == d {
+						profile = vv
+					} else if zoned == -1 || vv < zoned {
+						zoned = vv
+					}
+				}
+			}
+		}
+	}
+
+	if profile == -1 {
+		profile = zoned
+	}
+
+	if _, ok := r.Revoked["*"]; ok {
+		revoked = true
+	}
+
+	for k := range r.Revoked {
+		if r.matches(k, door) {
+			revoked = true
+		}
+	}
+
+	if granted && !revoked {
+		if profile != -1 {
+			return fmt.Sprintf("%v", profile)
+		}
+
+		return "Y"
+	}
+
+	return "N"
+}
+
+// Returns the reason for the ACL table entry for a door i.e. 'granted', 'revoked' or 'not granted',
+// with the time profile if access has been granted with a time profile.
+func (r record) reason(door string) string {
+	switch permission := r.permission(door); permission {
+	case "Y":
+		return "granted"
+
+	case "N":
+		if _, ok := r.Revoked["*"]; ok {
+			return "revoked"
+		}
+
+		for k := range r.Revoked {
+			if r.matches(k, door) {
+				return "revoked"
+			}
+		}
+
+		return "not granted"
+
+	default:
+		return fmt.Sprintf("granted (time profile %v)", permission)
+	}
+}
@@ -12,7 +12,7 @@ func TestGrantWithDoorsOnly(t *testing.T) {
 	expected := record{
 		Name:       "Harry Potter",
 		CardNumber: 6000001,
-		StartDate:  startOfYear(),
+		StartDate:  startOfYear(nil),
 		EndDate:    core.ToDate(2021, time.June, 30),
 		Granted: map[string]any{
 			"dungeon":    true,
@@ -25,7 +25,7 @@ func TestGrantWithDoorsOnly(t *testing.T) {
 	r := record{
 		Name:       "Harry Potter",
 		CardNumber: 6000001,
-		StartDate:  startOfYear(),
+		StartDate:  startOfYear(nil),
 		EndDate:    core.ToDate(2021, time.June, 30),
 		Granted:    map[string]any{},
 		Revoked:    map[string]struct{}{},
@@ -42,7 +42,7 @@ func TestGrantWithDoorProfile(t *testing.T) {
 	expected := record{
 		Name:       "Harry Potter",
 		CardNumber: 6000001,
-		StartDate:  startOfYear(),
+		StartDate:  startOfYear(nil),
 		EndDate:    core.ToDate(2021, time.June, 30),
 		Granted: map[string]any{
 			"dungeon":    29,
@@ -55,7 +55,7 @@ func TestGrantWithDoorProfile(t *testing.T) {
 	r := record{
 		Name:       "Harry Potter",
 		CardNumber: 6000001,
-		StartDate:  startOfYear(),
+		StartDate:  startOfYear(nil),
 		EndDate:    core.ToDate(2021, time.June, 30),
 		Granted:    map[string]any{},
 		Revoked:    map[string]struct{}{},
@@ -72,7 +72,7 @@ func TestGrantWithDoorAndProfile(t *testing.T) {
 	expected := record{
 		Name:       "Harry Potter",
 		CardNumber: 6000001,
-		StartDate:  startOfYear(),
+		StartDate:  startOfYear(nil),
 		EndDate:    core.ToDate(2021, time.June, 30),
 		Granted: map[string]any{
 			"dungeon": 29,
@@ -83,7 +83,7 @@ func TestGrantWithDoorAndProfile(t *testing.T) {
 	r := record{
 		Name:       "Harry Potter",
 		CardNumber: 6000001,
-		StartDate:  startOfYear(),
+		StartDate:  startOfYear(nil),
 		EndDate:    core.ToDate(2021, time.June, 30),
 		Granted:    map[string]any{},
 		Revoked:    map[string]struct{}{},
@@ -95,3 +95,77 @@ func TestGrantWithDoorAndProfile(t *testing.T) {
 		t.Errorf("'grant' failed\n   expected: %v\n   got:     %v", expected, r)
 	}
 }
+
+func TestGrantWithSchedule(t *testing.T) {
+	schedules := Schedules{
+		Schedule{Name: "weekday-evenings", Profile: core.TimeProfile{ID: 100}},
+		Schedule{Name: "Mealtimes", Profile: core.TimeProfile{ID: 29}},
+	}
+
+	expected := map[string]any{
+		"dungeon":            29,
+		"greathall":          100,
+		"gryffindor":         true,
+		"kitchen":            true,
+		"hogsmeade:weekends": true,
+	}
+
+	r := record{
+		Name:       "Harry Potter",
+		CardNumber: 6000001,
+		StartDate:  startOfYear(nil),
+		EndDate:    core.ToDate(2021, time.June, 30),
+		Granted:    map[string]any{},
+		Revoked:    map[string]struct{}{},
+		doors:      []string{"Great Hall", "Gryffindor", "Dungeon", "Kitchen", "Hogsmeade"},
+		schedules:  schedules,
+	}
+
+	r.Grant("Great Hall", "Weekday-Evenings")
+	r.Grant("Dungeon:mealtimes", "Gryffindor")
+	r.Grant("Kitchen", "Gryffindor")
+	r.Grant("Hogsmeade:weekends")
+
+	if !reflect.DeepEqual(r.Granted, expected) {
+		t.Errorf("'grant' failed\n   expected: %v\n   got:     %v", expected, r.Granted)
+	}
+}
+
+func TestGrantWithUnknownSchedule(t *testing.T) {
+	schedules := Schedules{
+		Schedule{Name: "weekday-evenings", Profile: core.TimeProfile{ID: 100}},
+	}
+
+	expected := map[string]any{
+		"greathall":  true,
+		"gryffindor": true,
+		"dungeon":    true,
+	}
+
+	r := record{
+		Name:       "Harry Potter",
+		CardNumber: 6000001,
+		StartDate:  startOfYear(nil),
+		EndDate:    core.ToDate(2021, time.June, 30),
+		Granted:    map[string]any{},
+		Revoked:    map[string]struct{}{},
+		doors:      []string{"Great Hall", "Gryffindor", "Hogsmeade"},
+		schedules:  schedules,
+		controllers: Controllers{
+			405419896: []string{"Great Hall", "Gryffindor", "Dungeon"},
+			303986753: []string{"Hogsmeade"},
+		},
+	}
+
+	r.Grant("Hogsmeade", "weekday-evemings")
+	r.Grant("Whomping Willow", "Great Hall")
+	r.Grant("Gryffindor", "Dungeon")
+
+	if !reflect.DeepEqual(r.Granted, expected) {
+		t.Errorf("'grant' failed\n   expected: %v\n   got:     %v", expected, r.Granted)
+	}
+
+	if permission := r.permission("Hogsmeade"); permission != "N" {
+		t.Errorf("'grant' with misspelt schedule granted access\n   expected: %v\n   got:     %v", "N", permission)
+	}
+}
@@ -6,21 +6,28 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"path"
 	"sort"
 	"strings"
+	"sync"
 
-	"github.com/hyperjumptech/grule-rule-engine/ast"
-	"github.com/hyperjumptech/grule-rule-engine/builder"
-	"github.com/hyperjumptech/grule-rule-engine/engine"
 	"github.com/hyperjumptech/grule-rule-engine/logger"
-	"github.com/hyperjumptech/grule-rule-engine/pkg"
 
 	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
 )
 
 type Rules struct {
-	hash    []byte
-	library *ast.KnowledgeLibrary
+	hash        []byte
+	ruleset     ruleset
+	validity    Validity
+	schedules   Schedules
+	zones       Zones
+	controllers Controllers
+	workers     int
+	evaluation  Evaluation
+	previous    map[uint32]record
+	revision    string
+	clock       types.Clock
 }
 
 func NewRules(ruleset []byte, debug bool) (*Rules, error) {
@@ -30,9 +37,29 @@ func NewRules(ruleset []byte, debug bool) (*Rules, error) {
 		logger.SetLogLevel(logger.ErrorLevel)
 	}
 
-	// ... check for header and footer
-	// Ref. https://github.com/uhppoted/uhppoted-app-wild-apricot/issues/2
+	if err := checkMarkers(ruleset); err != nil {
+		return nil, err
+	}
+
+	// ... parse
+	hash := sha256.Sum256(ruleset)
+	g, err := newGruleset(Source{Ruleset: ruleset})
+	if err != nil {
+		return nil, err
+	}
+
+	rules := Rules{
+		hash:    hash[:],
+		ruleset: g,
+	}
+
+	return &rules, nil
+}
 
+// Checks for the 'grules' file header and footer.
+//
+// Ref. https://github.com/uhppoted/uhppoted-app-wild-apricot/issues/2
+func checkMarkers(ruleset []byte) error {
 	first := ""
 	last := ""
 	b := bytes.NewBuffer(ruleset)
@@ -50,24 +77,40 @@ func NewRules(ruleset []byte, debug bool) (*Rules, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if !strings.Contains(first, "** GRULES **") || !strings.Contains(last, "*** END GRULES ***") {
-		return nil, fmt.Errorf("invalid 'grules' file - missing start/end markers")
+		return fmt.Errorf("invalid 'grules' file - missing start/end markers")
 	}
 
-	// ... parse
-	hash := sha256.Sum256(ruleset)
-	kb := ast.NewKnowledgeLibrary()
-	if err := builder.NewRuleBuilder(kb).BuildRuleFromResource("acl", "0.0.0", pkg.NewBytesResource(ruleset)); err != nil {
-		return nil, err
+	return nil
+}
+
+// ParseRules returns the rules for a 'grule' rules file, a YAML/JSON policy file or a CEL rules
+// file, using the file extension of the URI to determine the format (defaulting to 'grule').
+func ParseRules(uri string, ruleset []byte, debug bool) (*Rules, error) {
+	switch Format(uri) {
+	case ".yaml", ".yml", ".json":
+		return NewPolicy(ruleset, debug)
+
+	case ".cel":
+		return NewCELRules(ruleset, debug)
+
+	default:
+		return NewRules(ruleset, debug)
 	}
+}
 
-	return &Rules{
-		hash:    hash[:],
-		library: kb,
-	}, nil
+// Format returns the (lowercase) file extension of a rules URI, ignoring any query string
+// e.g. .grl or .yaml.
+func Format(uri string) string {
+	p := uri
+	if ix := strings.IndexAny(p, "?#"); ix >= 0 {
+		p = p[:ix]
+	}
+
+	return strings.ToLower(path.Ext(p))
 }
 
 func (rules *Rules) Updated(hash string) bool {
@@ -79,62 +122,118 @@ func (rules *Rules) Updated(hash string) bool {
 }
 
 func (rules *Rules) MakeACL(members types.Members, doors []string) (*ACL, error) {
-	acl := ACL{
-		doors:   doors,
-		records: []record{},
+	return rules.makeACL(members, doors, false)
+}
+
+func (rules *Rules) MakeACLWithPIN(members types.Members, doors []string) (*ACL, error) {
+	return rules.makeACL(members, doors, true)
+}
+
+// Sets the policy used to assign the default card start and end dates.
+func (rules *Rules) SetDefaultValidity(validity Validity) {
+	if rules != nil {
+		rules.validity = validity
 	}
+}
 
-	for _, m := range members.Members {
-		r := record{
-			Name:      m.Name,
-			StartDate: startOfYear(),
-			EndDate:   endOfYear(),
-			Granted:   map[string]any{},
-			Revoked:   map[string]struct{}{},
-		}
+// Sets the named schedules that can be used to grant time based access to a door.
+func (rules *Rules) SetSchedules(schedules Schedules) {
+	if rules != nil {
+		rules.schedules = schedules
+	}
+}
 
-		if m.CardNumber != nil {
-			r.CardNumber = uint32(*m.CardNumber)
-		}
+// Sets the named door groups that can be granted or revoked as a unit e.g. 'zone:Workshop'.
+func (rules *Rules) SetZones(zones Zones) {
+	if rules != nil {
+		rules.zones = zones
+	}
+}
 
-		if err := rules.eval(m, &r); err != nil {
-			return nil, err
-		}
+// Sets the doors for each controller, for 'controller:<id>' grants and revokes.
+func (rules *Rules) SetControllers(controllers Controllers) {
+	if rules != nil {
+		rules.controllers = controllers
+	}
+}
 
-		if r.CardNumber > 0 {
-			acl.records = append(acl.records, r)
-		}
+// Sets the number of workers used to evaluate the rules for the members in parallel. The
+// rules are evaluated sequentially if the number of workers is less than 2.
+func (rules *Rules) SetWorkers(workers int) {
+	if rules != nil {
+		rules.workers = workers
 	}
+}
 
-	sort.SliceStable(acl.records, func(i, j int) bool { return acl.records[i].CardNumber < acl.records[j].CardNumber })
+// Sets the source revision of the rules e.g. the commit hash for rules retrieved from a git
+// repository.
+func (rules *Rules) SetRevision(revision string) {
+	if rules != nil {
+		rules.revision = revision
+	}
+}
 
-	return &acl, nil
+// Returns the source revision of the rules (if any).
+func (rules *Rules) Revision() string {
+	if rules != nil {
+		return rules.revision
+	}
+
+	return ""
 }
 
-func (rules *Rules) MakeACLWithPIN(members types.Members, doors []string) (*ACL, error) {
+// Sets the clock used for the default card validity and the date based member functions e.g. to
+// generate the ACL as of a future date. Defaults to the system clock.
+func (rules *Rules) SetClock(clock types.Clock) {
+	if rules != nil {
+		rules.clock = clock
+	}
+}
+
+func (rules *Rules) makeACL(members types.Members, doors []string, withPIN bool) (*ACL, error) {
 	acl := ACL{
 		doors:   doors,
 		records: []record{},
 	}
 
-	for _, m := range members.Members {
-		r := record{
-			Name:      m.Name,
-			PIN:       m.PIN,
-			StartDate: startOfYear(),
-			EndDate:   endOfYear(),
-			Granted:   map[string]any{},
-			Revoked:   map[string]struct{}{},
+	records := make([]record, len(members.Members))
+	for i, m := range members.Members {
+		records[i] = rules.newRecord(m, doors, withPIN)
+	}
+
+	errors := make([]error, len(members.Members))
+
+	if rules.workers > 1 && len(members.Members) > 1 {
+		rules.evalParallel(members.Members, records, errors)
+	} else {
+		for i, m := range members.Members {
+			if errors[i] = rules.eval(m, &records[i], nil); errors[i] != nil && rules.evaluation.OnError == Abort {
+				break
+			}
 		}
+	}
 
-		if m.CardNumber != nil {
-			r.CardNumber = uint32(*m.CardNumber)
+	for i, err := range errors {
+		if err == nil {
+			continue
 		}
 
-		if err := rules.eval(m, &r); err != nil {
-			return nil, err
+		m := members.Members[i]
+		if rules.evaluation.OnError == Abort {
+			return nil, fmt.Errorf("error evaluating rules for %v (%v)", m.Name, err)
 		}
 
+		rules.fallback(&records[i], rules.newRecord(m, doors, withPIN))
+
+		acl.failures = append(acl.failures, Failure{
+			MemberID:   m.ID,
+			Name:       m.Name,
+			CardNumber: records[i].CardNumber,
+			Err:        err,
+		})
+	}
+
+	for _, r := range records {
 		if r.CardNumber > 0 {
 			acl.records = append(acl.records, r)
 		}
@@ -145,6 +244,34 @@ func (rules *Rules) MakeACLWithPIN(members types.Members, doors []string) (*ACL,
 	return &acl, nil
 }
 
+// Initialises the ACL record for a member with the default start and end dates.
+func (rules *Rules) newRecord(m types.Member, doors []string, withPIN bool) record {
+	start, end := rules.validity.dates(m, rules.clock)
+
+	r := record{
+		MemberID:    m.ID,
+		Name:        m.Name,
+		StartDate:   start,
+		EndDate:     end,
+		Granted:     map[string]any{},
+		Revoked:     map[string]struct{}{},
+		doors:       doors,
+		schedules:   rules.schedules,
+		zones:       rules.zones,
+		controllers: rules.controllers,
+	}
+
+	if withPIN {
+		r.PIN = m.PIN
+	}
+
+	if m.CardNumber != nil {
+		r.CardNumber = uint32(*m.CardNumber)
+	}
+
+	return r
+}
+
 func (rules *Rules) Hash() string {
 	if rules != nil {
 		return hex.EncodeToString(rules.hash)
@@ -153,22 +280,27 @@ func (rules *Rules) Hash() string {
 	return ""
 }
 
-func (rules *Rules) eval(m types.Member, r *record) error {
-	context := ast.NewDataContext()
+func (rules *Rules) evalParallel(members []types.Member, records []record, errors []error) {
+	var wg sync.WaitGroup
 
-	if err := context.Add("member", &m); err != nil {
-		return err
+	queue := make(chan int)
+
+	for range min(rules.workers, len(members)) {
+		wg.Go(func() {
+			for i := range queue {
+				errors[i] = rules.eval(members[i], &records[i], nil)
+			}
+		})
 	}
 
-	if err := context.Add("permissions", r); err != nil {
-		return err
+	for i := range members {
+		queue <- i
 	}
 
-	enjin := engine.NewGruleEngine()
+	close(queue)
+	wg.Wait()
+}
 
-	if kb, err := rules.library.NewKnowledgeBaseInstance("acl", "0.0.0"); err != nil {
-		return err
-	} else {
-		return enjin.Execute(context, kb)
-	}
+func (rules *Rules) eval(m types.Member, r *record, trace tracer) error {
+	return rules.ruleset.eval(types.WithClock(m, rules.clock), r, rules.evaluation, trace)
 }
@@ -152,7 +152,7 @@ func TestMakeACL(t *testing.T) {
 				Name:       "Albus Dumbledore",
 				CardNumber: 1000001,
 				StartDate:  core.ToDate(1880, time.February, 29),
-				EndDate:    endOfYear(),
+				EndDate:    endOfYear(nil),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
 			},
@@ -160,14 +160,14 @@ func TestMakeACL(t *testing.T) {
 				Name:       "Tom Riddle",
 				CardNumber: 2000001,
 				StartDate:  core.ToDate(1981, time.July, 1),
-				EndDate:    endOfYear(),
+				EndDate:    endOfYear(nil),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
 			},
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
@@ -237,7 +237,7 @@ func TestMakeACLWithDuplicateCards(t *testing.T) {
 				Name:       "Albus Dumbledore",
 				CardNumber: 1000001,
 				StartDate:  core.ToDate(1880, time.February, 29),
-				EndDate:    endOfYear(),
+				EndDate:    endOfYear(nil),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
 			},
@@ -245,14 +245,14 @@ func TestMakeACLWithDuplicateCards(t *testing.T) {
 				Name:       "Tom Riddle",
 				CardNumber: 2000001,
 				StartDate:  core.ToDate(1981, time.July, 1),
-				EndDate:    endOfYear(),
+				EndDate:    endOfYear(nil),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
 			},
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
@@ -269,7 +269,7 @@ func TestMakeACLWithDuplicateCards(t *testing.T) {
 				Name:       "Aberforth Dumbledore",
 				CardNumber: 1000001,
 				StartDate:  core.ToDate(2001, time.February, 28),
-				EndDate:    endOfYear(),
+				EndDate:    endOfYear(nil),
 				Granted:    map[string]any{},
 				Revoked:    map[string]struct{}{},
 			},
@@ -300,6 +300,57 @@ func TestMakeACLWithDuplicateCards(t *testing.T) {
 	}
 }
 
+func TestMakeACLWithDefaultValidity(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry},
+	}
+
+	doors := []string{}
+
+	expected := ACL{
+		records: []record{
+			record{
+				Name:       "Albus Dumbledore",
+				CardNumber: 1000001,
+				StartDate:  core.ToDate(1880, time.February, 29),
+				EndDate:    core.ToDate(2099, time.December, 31),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+			record{
+				Name:       "Harry Potter",
+				CardNumber: 6000001,
+				StartDate:  startOfYear(nil),
+				EndDate:    core.ToDate(2021, time.June, 30),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+		},
+	}
+
+	r, err := NewRules([]byte(grules), true)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	r.SetDefaultValidity(Validity{Policy: FarFuture})
+
+	for _, f := range []func(types.Members, []string) (*ACL, error){r.MakeACL, r.MakeACLWithPIN} {
+		acl, err := f(members, doors)
+		if err != nil {
+			t.Fatalf("Unexpected error (%v)", err)
+		}
+
+		if len(acl.records) != len(expected.records) {
+			t.Errorf("Invalid ACL - expected %v records, got %v", len(expected.records), len(acl.records))
+		} else {
+			for i := range expected.records {
+				compare(acl.records[i], expected.records[i], t)
+			}
+		}
+	}
+}
+
 func TestGrant(t *testing.T) {
 	members := types.Members{
 		Members: []types.Member{harry},
@@ -312,7 +363,7 @@ func TestGrant(t *testing.T) {
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted: map[string]any{
 					"whompingwillow": true,
@@ -353,7 +404,7 @@ func TestGrantWithDoorWithTimeProfile(t *testing.T) {
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted: map[string]any{
 					"whompingwillow": 29,
@@ -394,7 +445,7 @@ func TestGrantWithDoorAndTimeProfile(t *testing.T) {
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted: map[string]any{
 					"whompingwillow": 55,
@@ -434,7 +485,7 @@ func TestVariadicGrant(t *testing.T) {
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted: map[string]any{
 					"whompingwillow": true,
@@ -477,6 +528,68 @@ rule Grant "Grants permission to the Whomping Willow" {
 	}
 }
 
+func TestGrantWithNamedSchedule(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{harry},
+	}
+
+	doors := []string{}
+
+	expected := ACL{
+		records: []record{
+			record{
+				Name:       "Harry Potter",
+				CardNumber: 6000001,
+				StartDate:  startOfYear(nil),
+				EndDate:    core.ToDate(2021, time.June, 30),
+				Granted: map[string]any{
+					"whompingwillow": 100,
+					"greathall":      29,
+				},
+				Revoked: map[string]struct{}{},
+			},
+		},
+	}
+
+	grant := `
+// *** GRULES ***
+rule Grant "Grants permission to the Whomping Willow on weekday evenings and the Great Hall at mealtimes" {
+     when
+		member.HasCardNumber(6000001)
+	 then
+         permissions.Grant("Whomping Willow", "weekday-evenings");
+         permissions.Grant("Great Hall:mealtimes");
+         Retract("Grant");
+}
+// *** END GRULES ***
+`
+
+	r, err := NewRules([]byte(grules+grant), true)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	r.SetSchedules(Schedules{
+		Schedule{Name: "weekday-evenings", Profile: core.TimeProfile{ID: 100}},
+		Schedule{Name: "Mealtimes", Profile: core.TimeProfile{ID: 29}},
+	})
+
+	acl, err := r.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(acl.records) != len(expected.records) {
+		t.Errorf("Invalid ACL - expected %v records, got %v", len(expected.records), len(acl.records))
+	} else {
+		for i := range expected.records {
+			if !reflect.DeepEqual(acl.records[i].Granted, expected.records[i].Granted) {
+				t.Errorf("Invalid ACL record 'granted' - expected:%#v, got:%#v", expected.records[i].Granted, acl.records[i].Granted)
+			}
+		}
+	}
+}
+
 func TestRevoke(t *testing.T) {
 	members := types.Members{
 		Members: []types.Member{harry},
@@ -489,7 +602,7 @@ func TestRevoke(t *testing.T) {
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted:    map[string]any{},
 				Revoked: map[string]struct{}{
@@ -530,7 +643,7 @@ func TestVariadicRevoke(t *testing.T) {
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted:    map[string]any{},
 				Revoked: map[string]struct{}{
@@ -585,7 +698,7 @@ func TestGrantAndRevoke(t *testing.T) {
 			record{
 				Name:       "Harry Potter",
 				CardNumber: 6000001,
-				StartDate:  startOfYear(),
+				StartDate:  startOfYear(nil),
 				EndDate:    core.ToDate(2021, time.June, 30),
 				Granted: map[string]any{
 					"whompingwillow": true,
@@ -616,7 +729,267 @@ func TestGrantAndRevoke(t *testing.T) {
 	}
 }
 
+func TestExtendEndDate(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{harry},
+	}
+
+	doors := []string{}
+
+	expected := ACL{
+		records: []record{
+			record{
+				Name:       "Harry Potter",
+				CardNumber: 6000001,
+				StartDate:  startOfYear(nil),
+				EndDate:    core.ToDate(2021, time.July, 14),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+		},
+	}
+
+	grace := `
+// *** GRULES ***
+rule EndDate "Sets the end date to the 'expires' field plus a 14 day grace period" {
+     when
+		member.HasExpires()
+	 then
+         permissions.SetEndDate(member.Expires);
+         permissions.ExtendEndDate(14);
+         Retract("EndDate");
+}
+// *** END GRULES ***
+`
+
+	r, err := NewRules([]byte(grace), true)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	acl, err := r.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(acl.records) != len(expected.records) {
+		t.Errorf("Invalid ACL - expected %v records, got %v", len(expected.records), len(acl.records))
+	} else {
+		for i := range expected.records {
+			compare(acl.records[i], expected.records[i], t)
+		}
+	}
+}
+
+func TestExpiredFor(t *testing.T) {
+	lapsed := harry
+	lapsed.Expires = plusDays(today(nil), -20)
+
+	renewing := hermione
+	renewing.Expires = plusDays(today(nil), -10)
+
+	members := types.Members{
+		Members: []types.Member{dumbledore, lapsed, renewing},
+	}
+
+	doors := []string{}
+
+	expected := ACL{
+		records: []record{
+			record{
+				Name:       "Albus Dumbledore",
+				CardNumber: 1000001,
+				StartDate:  startOfYear(nil),
+				EndDate:    endOfYear(nil),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+			record{
+				Name:       "Harry Potter",
+				CardNumber: 6000001,
+				StartDate:  startOfYear(nil),
+				EndDate:    endOfYear(nil),
+				Granted:    map[string]any{},
+				Revoked: map[string]struct{}{
+					"*": struct{}{},
+				},
+			},
+			record{
+				Name:       "Hermione Granger",
+				CardNumber: 6000002,
+				StartDate:  startOfYear(nil),
+				EndDate:    endOfYear(nil),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+		},
+	}
+
+	grace := `
+// *** GRULES ***
+rule Lapsed "Revokes all access 14 days after the renewal date" {
+     when
+		member.ExpiredFor(14)
+	 then
+         permissions.Revoke("*");
+         Retract("Lapsed");
+}
+// *** END GRULES ***
+`
+
+	r, err := NewRules([]byte(grace), true)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	acl, err := r.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(acl.records) != len(expected.records) {
+		t.Errorf("Invalid ACL - expected %v records, got %v", len(expected.records), len(acl.records))
+	} else {
+		for i := range expected.records {
+			compare(acl.records[i], expected.records[i], t)
+		}
+	}
+}
+
+func TestDaysUntilExpiry(t *testing.T) {
+	expiring := harry
+	expiring.Expires = plusDays(today(nil), 10)
+
+	renewed := hermione
+	renewed.Expires = plusDays(today(nil), 90)
+
+	members := types.Members{
+		Members: []types.Member{dumbledore, expiring, renewed},
+	}
+
+	doors := []string{}
+
+	expected := ACL{
+		records: []record{
+			record{
+				Name:       "Albus Dumbledore",
+				CardNumber: 1000001,
+				StartDate:  startOfYear(nil),
+				EndDate:    endOfYear(nil),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+			record{
+				Name:       "Harry Potter",
+				CardNumber: 6000001,
+				StartDate:  startOfYear(nil),
+				EndDate:    endOfYear(nil),
+				Granted: map[string]any{
+					"renewalsdesk": true,
+				},
+				Revoked: map[string]struct{}{},
+			},
+			record{
+				Name:       "Hermione Granger",
+				CardNumber: 6000002,
+				StartDate:  startOfYear(nil),
+				EndDate:    endOfYear(nil),
+				Granted:    map[string]any{},
+				Revoked:    map[string]struct{}{},
+			},
+		},
+	}
+
+	expiry := `
+// *** GRULES ***
+rule Expiring "Grants access to the renewals desk in the 30 days before the renewal date" {
+     when
+		member.HasExpires() && member.DaysUntilExpiry() >= 0 && member.DaysUntilExpiry() < 30
+	 then
+         permissions.Grant("Renewals Desk");
+         Retract("Expiring");
+}
+// *** END GRULES ***
+`
+
+	r, err := NewRules([]byte(expiry), true)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	acl, err := r.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(acl.records) != len(expected.records) {
+		t.Errorf("Invalid ACL - expected %v records, got %v", len(expected.records), len(acl.records))
+	} else {
+		for i := range expected.records {
+			compare(acl.records[i], expected.records[i], t)
+		}
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	lapsed := harry
+	lapsed.Expires = core.MustParseDate("2026-06-30")
+
+	renewing := hermione
+	renewing.Active = true
+	renewing.Expires = core.MustParseDate("2026-07-10")
+
+	members := types.Members{
+		Members: []types.Member{lapsed, renewing},
+	}
+
+	doors := []string{"Great Hall"}
+
+	expected := [][]string{
+		{"6000001", "2026-01-01", "2026-08-19", "N"},
+		{"6000002", "2026-01-01", "2026-08-19", "Y"},
+	}
+
+	grace := `
+// *** GRULES ***
+rule Members "Grants all members access to the Great Hall" {
+     when
+		member.IsActive()
+	 then
+         permissions.Grant("Great Hall");
+         Retract("Members");
+}
+
+rule Lapsed "Revokes all access 14 days after the renewal date" {
+     when
+		member.ExpiredFor(14)
+	 then
+         permissions.Revoke("*");
+         Retract("Lapsed");
+}
+// *** END GRULES ***
+`
+
+	r, err := NewRules([]byte(grace), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	r.SetDefaultValidity(Validity{Policy: Rolling, Days: 30})
+	r.SetClock(types.AsOf(core.MustParseDate("2026-07-20")))
+
+	acl, err := r.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if rows := acl.AsTable().Records; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, rows)
+	}
+}
 func compare(r, expected record, t *testing.T) {
+	expected.doors = r.doors // ... the ACL doors are not part of the expected record
+
 	if reflect.DeepEqual(r, expected) {
 		return
 	}
@@ -0,0 +1,23 @@
+package acl
+
+import (
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// ruleset is the rules engine used to evaluate the access rules for a member. The 'grule' and
+// CEL implementations share the ACL generation, failure policy and explain logic in Rules.
+type ruleset interface {
+	// Evaluates the rules for a member, updating the record. The (optional) tracer is notified
+	// before each rule is executed.
+	eval(m types.Member, r *record, evaluation Evaluation, trace tracer) error
+
+	// Checks the rules against the doors, groups and membership levels in the vocabulary.
+	lint(v Vocabulary, schedules Schedules) []string
+}
+
+// tracer is notified before a rule is executed (execute) and at the start of each evaluation
+// cycle (flush).
+type tracer interface {
+	execute(cycle uint64, rule string, description string)
+	flush()
+}
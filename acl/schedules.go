@@ -0,0 +1,127 @@
+package acl
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+)
+
+// Schedule is a named time profile that can be used in the rules to grant time based
+// access to a door, e.g. permissions.Grant("Workshop", "weekday-evenings"). The time
+// profiles are created (or updated) on the controllers by load-acl, so they do not need
+// to be predefined.
+type Schedule struct {
+	Name    string
+	Profile core.TimeProfile
+}
+
+type Schedules []Schedule
+
+// Default 'from' and 'to' dates for a schedule that does not define a date range.
+var scheduleFrom = core.ToDate(2000, time.January, 1)
+var scheduleTo = core.ToDate(2099, time.December, 31)
+
+// Parses a JSON schedules file, which is a list of time profiles with names, e.g.:
+//
+//	[
+//	  {
+//	    "name": "weekday-evenings",
+//	    "id": 100,
+//	    "start-date": "2026-01-01",
+//	    "end-date": "2026-12-31",
+//	    "weekdays": "Monday,Tuesday,Wednesday,Thursday,Friday",
+//	    "segments": [
+//	      { "start": "17:00", "end": "21:00" }
+//	    ]
+//	  }
+//	]
+//
+// The 'id' is the controller time profile ID (2-254) and the start and end dates are optional.
+func ParseSchedules(bytes []byte) (Schedules, error) {
+	list := []json.RawMessage{}
+	if err := json.Unmarshal(bytes, &list); err != nil {
+		return nil, err
+	}
+
+	schedules := Schedules{}
+	names := map[string]bool{}
+	profiles := map[uint8]string{}
+
+	for _, v := range list {
+		schedule := struct {
+			Name string `json:"name"`
+		}{}
+
+		profile := core.TimeProfile{}
+
+		if err := json.Unmarshal(v, &schedule); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(v, &profile); err != nil {
+			return nil, fmt.Errorf("schedule '%v': %v", schedule.Name, err)
+		}
+
+		name := normalise(schedule.Name)
+
+		if name == "" {
+			return nil, fmt.Errorf("schedule with time profile %v is missing a name", profile.ID)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("duplicate schedule '%v'", schedule.Name)
+		}
+
+		if profile.ID < 2 || profile.ID > 254 {
+			return nil, fmt.Errorf("schedule '%v': invalid time profile ID (%v)", schedule.Name, profile.ID)
+		}
+
+		if other, ok := profiles[profile.ID]; ok {
+			return nil, fmt.Errorf("schedules '%v' and '%v' have the same time profile ID (%v)", other, schedule.Name, profile.ID)
+		}
+
+		if profile.From.IsZero() {
+			profile.From = scheduleFrom
+		}
+
+		if profile.To.IsZero() {
+			profile.To = scheduleTo
+		}
+
+		if profile.To.Before(profile.From) {
+			return nil, fmt.Errorf("schedule '%v': end date (%v) is before start date (%v)", schedule.Name, profile.To, profile.From)
+		}
+
+		for _, k := range []uint8{1, 2, 3} {
+			if segment := profile.Segments[k]; segment.End.Before(segment.Start) {
+				return nil, fmt.Errorf("schedule '%v': segment %v end is before start (%v)", schedule.Name, k, segment)
+			}
+		}
+
+		names[name] = true
+		profiles[profile.ID] = schedule.Name
+		schedules = append(schedules, Schedule{
+			Name:    schedule.Name,
+			Profile: profile,
+		})
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool { return schedules[i].Profile.ID < schedules[j].Profile.ID })
+
+	return schedules, nil
+}
+
+// Returns the time profile ID for a schedule name.
+func (schedules Schedules) lookup(name string) (int, bool) {
+	s := normalise(name)
+	for _, schedule := range schedules {
+		if normalise(schedule.Name) == s {
+			return int(schedule.Profile.ID), true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,87 @@
+package acl
+
+import (
+	"testing"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+)
+
+var schedules = `[
+  {
+    "name": "weekday-evenings",
+    "id": 100,
+    "weekdays": "Monday,Tuesday,Wednesday,Thursday,Friday",
+    "segments": [
+      { "start": "17:00", "end": "21:00" }
+    ]
+  },
+  {
+    "name": "Mealtimes",
+    "id": 29,
+    "start-date": "2026-01-01",
+    "end-date": "2026-12-31",
+    "weekdays": "Monday,Tuesday,Wednesday,Thursday,Friday,Saturday,Sunday",
+    "segments": [
+      { "start": "07:00", "end": "08:30" },
+      { "start": "12:00", "end": "13:30" },
+      { "start": "18:00", "end": "19:30" }
+    ]
+  }
+]`
+
+func TestParseSchedules(t *testing.T) {
+	list, err := ParseSchedules([]byte(schedules))
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(list) != 2 {
+		t.Fatalf("Incorrect number of schedules - expected:%v, got:%v", 2, len(list))
+	}
+
+	mealtimes := list[0]
+	evenings := list[1]
+
+	if mealtimes.Name != "Mealtimes" || mealtimes.Profile.ID != 29 {
+		t.Errorf("Incorrect schedule - expected:%v/%v, got:%v/%v", "Mealtimes", 29, mealtimes.Name, mealtimes.Profile.ID)
+	}
+
+	if !mealtimes.Profile.From.Equals(core.ToDate(2026, time.January, 1)) || !mealtimes.Profile.To.Equals(core.ToDate(2026, time.December, 31)) {
+		t.Errorf("Incorrect schedule dates - expected:%v:%v, got:%v:%v", "2026-01-01", "2026-12-31", mealtimes.Profile.From, mealtimes.Profile.To)
+	}
+
+	if s := mealtimes.Profile.Segments.String(); s != "07:00-08:30,12:00-13:30,18:00-19:30" {
+		t.Errorf("Incorrect schedule segments - expected:%v, got:%v", "07:00-08:30,12:00-13:30,18:00-19:30", s)
+	}
+
+	if evenings.Name != "weekday-evenings" || evenings.Profile.ID != 100 {
+		t.Errorf("Incorrect schedule - expected:%v/%v, got:%v/%v", "weekday-evenings", 100, evenings.Name, evenings.Profile.ID)
+	}
+
+	if !evenings.Profile.From.Equals(scheduleFrom) || !evenings.Profile.To.Equals(scheduleTo) {
+		t.Errorf("Incorrect default schedule dates - expected:%v:%v, got:%v:%v", scheduleFrom, scheduleTo, evenings.Profile.From, evenings.Profile.To)
+	}
+
+	if w := evenings.Profile.Weekdays.String(); w != "Mon,Tue,Wed,Thurs,Fri" {
+		t.Errorf("Incorrect schedule weekdays - expected:%v, got:%v", "Mon,Tue,Wed,Thurs,Fri", w)
+	}
+}
+
+func TestParseInvalidSchedules(t *testing.T) {
+	tests := []string{
+		`[ { "id": 100 } ]`,
+		`[ { "name": "evenings", "id": 1 } ]`,
+		`[ { "name": "evenings", "id": 255 } ]`,
+		`[ { "name": "evenings", "id": 100 }, { "name": "Evenings", "id": 101 } ]`,
+		`[ { "name": "evenings", "id": 100 }, { "name": "mornings", "id": 100 } ]`,
+		`[ { "name": "evenings", "id": 100, "start-date": "2026-12-31", "end-date": "2026-01-01" } ]`,
+		`[ { "name": "evenings", "id": 100, "segments": [ { "start": "21:00", "end": "17:00" } ] } ]`,
+	}
+
+	for _, test := range tests {
+		if _, err := ParseSchedules([]byte(test)); err == nil {
+			t.Errorf("Expected error parsing schedules %v", test)
+		}
+	}
+}
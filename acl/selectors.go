@@ -0,0 +1,152 @@
+package acl
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Controllers is the list of doors for each controller, for 'controller:<id>' grants and revokes.
+type Controllers map[uint32][]string
+
+// Compiled regular expression door patterns and Grant argument expressions, keyed by pattern.
+var patterns sync.Map
+
+// Parses a Grant argument into the door (or zone, controller or door pattern) and the granted
+// value i.e. true or the time profile ID for an explicit time profile or named schedule e.g.
+// "Great Hall:29" or "zone:Workshop:weekday-evenings".
+func parseGrant(arg string, schedules Schedules) (string, any) {
+	prefix, s := splitSelector(arg)
+
+	// ... regular expression with optional time profile/schedule
+	if strings.HasPrefix(strings.TrimSpace(s), "/") {
+		s = strings.TrimSpace(s)
+		if ix := strings.LastIndex(s, "/"); ix > 0 && strings.HasPrefix(s[ix+1:], ":") {
+			if profile, err := strconv.Atoi(s[ix+2:]); err == nil {
+				return prefix + s[:ix+1], profile
+			} else if profile, ok := schedules.lookup(s[ix+2:]); ok {
+				return prefix + s[:ix+1], profile
+			}
+		}
+
+		return prefix + s, true
+	}
+
+	if match := compiled(`(\S.*?):([0-9]+)`).FindStringSubmatch(s); match != nil {
+		profile, _ := strconv.Atoi(match[2])
+		return prefix + match[1], profile
+	}
+
+	if match := compiled(`(\S.*?):(\S.*)`).FindStringSubmatch(s); match != nil {
+		if profile, ok := schedules.lookup(match[2]); ok {
+			return prefix + match[1], profile
+		}
+	}
+
+	return prefix + s, true
+}
+
+// Splits a Grant/Revoke 'zone:<name>' or 'controller:<id>' argument into the selector prefix and
+// the zone name or controller ID. Returns a blank prefix and the argument unchanged for a door
+// or door pattern.
+func splitSelector(arg string) (string, string) {
+	s := strings.TrimSpace(arg)
+	for _, prefix := range []string{"zone:", "controller:"} {
+		if len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			return prefix, strings.TrimSpace(s[len(prefix):])
+		}
+	}
+
+	return "", arg
+}
+
+// Returns the Granted/Revoked key for a door, zone, controller or door pattern. Regular expressions
+// are kept as is, everything else is normalised.
+func key(door string) string {
+	if prefix, s := splitSelector(door); prefix == "" && isRegex(strings.TrimSpace(s)) {
+		return strings.TrimSpace(s)
+	}
+
+	return normalise(door)
+}
+
+// A regular expression door pattern is delimited by '/' e.g. /^Studio [0-9]+$/.
+func isRegex(s string) bool {
+	return len(s) > 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/")
+}
+
+// A glob door pattern contains '*', '?' or '[' e.g. Studio* (but not '*' which grants/revokes
+// all doors).
+func isGlob(s string) bool {
+	return s != "*" && strings.ContainsAny(s, "*?[")
+}
+
+// Returns the cached compiled regular expression for one of the Grant argument expressions.
+func compiled(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+
+	re := regexp.MustCompile(expr)
+
+	patterns.Store(expr, re)
+
+	return re
+}
+
+func regex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(pattern, re)
+
+	return re, nil
+}
+
+// Returns true if a Granted/Revoked key matches a door, expanding 'zone:<name>' and
+// 'controller:<id>' keys to the doors in the zone/on the controller. Glob patterns are matched
+// against the normalised door name and regular expressions against the door name (ignoring case).
+func matches(key string, door string, zones Zones, controllers Controllers) bool {
+	prefix, name := splitSelector(key)
+
+	switch {
+	case prefix == "zone:":
+		return zones.contains(name, door)
+
+	case prefix == "controller:":
+		return controllers.contains(name, door)
+
+	case isRegex(key):
+		re, err := regex(key)
+		return err == nil && re.MatchString(door)
+
+	case isGlob(key):
+		if normalise(key) == normalise(door) {
+			return true
+		}
+
+		ok, _ := path.Match(normalise(key), normalise(door))
+		return ok
+
+	default:
+		return normalise(key) == normalise(door)
+	}
+}
+
+// Returns true if the controller includes the door.
+func (c Controllers) contains(controller string, door string) bool {
+	if id, err := strconv.ParseUint(controller, 10, 32); err == nil {
+		return slices.ContainsFunc(c[uint32(id)], func(d string) bool { return normalise(d) == normalise(door) })
+	}
+
+	return false
+}
@@ -0,0 +1,172 @@
+package acl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var controllers = Controllers{
+	405419896: []string{"Studio 1", "Studio 2"},
+	303986753: []string{"Great Hall", "Gryffindor"},
+}
+
+var selectors = `
+// *** GRULES ***
+rule Staff "Grants staff access to all doors except the doors on controller 303986753" {
+     when
+		member.HasCardNumber(1000001)
+	 then
+         permissions.Grant("*");
+         permissions.Revoke("controller:303986753");
+         Retract("Staff");
+}
+
+rule Students "Grants students access to the studios" {
+     when
+		member.HasCardNumber(6000001)
+	 then
+         permissions.Grant("Studio *");
+         Retract("Students");
+}
+
+rule Prefects "Grants prefects access to the Great Hall and studios" {
+     when
+		member.HasCardNumber(6000002)
+	 then
+         permissions.Grant("/^great/:29", "controller:405419896:30");
+         permissions.Revoke("/^Studio [2-9]$/");
+         Retract("Prefects");
+}
+// *** END GRULES ***
+`
+
+func TestSelectors(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry, hermione},
+	}
+
+	doors := []string{"Great Hall", "Gryffindor", "Studio 1", "Studio 2"}
+
+	expected := [][]string{
+		{"1000001", "N", "N", "Y", "Y"},
+		{"6000001", "N", "N", "Y", "Y"},
+		{"6000002", "29", "N", "30", "N"},
+	}
+
+	rules, err := NewRules([]byte(selectors), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetControllers(controllers)
+
+	acl, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if rows := permissions(acl); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, rows)
+	}
+}
+
+func TestParseGrant(t *testing.T) {
+	tests := []struct {
+		arg     string
+		door    string
+		granted any
+	}{
+		{"Great Hall", "Great Hall", true},
+		{"Great Hall:29", "Great Hall", 29},
+		{"Studio *:29", "Studio *", 29},
+		{"zone:Workshop:30", "zone:Workshop", 30},
+		{"controller:405419896", "controller:405419896", true},
+		{"controller:405419896:29", "controller:405419896", 29},
+		{"/^Studio [0-9]+$/", "/^Studio [0-9]+$/", true},
+		{"/^Studio [0-9]+$/:29", "/^Studio [0-9]+$/", 29},
+		{"/^Studio:[0-9]+$/", "/^Studio:[0-9]+$/", true},
+	}
+
+	for _, test := range tests {
+		door, granted := parseGrant(test.arg, nil)
+
+		if door != test.door || granted != test.granted {
+			t.Errorf("Incorrect grant for '%v'\n   expected:%v %v\n   got:     %v %v", test.arg, test.door, test.granted, door, granted)
+		}
+	}
+}
+
+func TestMatchesGlobCharacters(t *testing.T) {
+	tests := []struct {
+		key     string
+		door    string
+		matches bool
+	}{
+		{"Studio [A]", "Studio [A]", true},
+		{"studio [a]", "Studio [A]", true},
+		{"Studio [A]", "Studio A", true},
+		{"Studio [A]", "Studio B", false},
+		{"Studio ?", "Studio 1", true},
+	}
+
+	for _, test := range tests {
+		if ok := matches(test.key, test.door, nil, nil); ok != test.matches {
+			t.Errorf("Incorrect match for '%v' and '%v'\n   expected:%v\n   got:     %v", test.key, test.door, test.matches, ok)
+		}
+	}
+}
+
+func TestSelectorsLint(t *testing.T) {
+	expected := []string{
+		"rule Prefects: door pattern '/^Kitchen/' does not match any door",
+		"rule Prefects: invalid door pattern '/^Studio [/' (error parsing regexp: missing closing ]: `[`)",
+		"rule Staff: unknown controller 303986753",
+		"rule Students: door pattern 'Dungeon *' does not match any door",
+		"rule Students: invalid controller 'Dungeons'",
+	}
+
+	rules, err := NewRules([]byte(`
+// *** GRULES ***
+rule Staff "Grants staff access to all doors except the doors on controller 303986753" {
+     when
+		member.HasCardNumber(1000001)
+	 then
+         permissions.Grant("*");
+         permissions.Revoke("controller:303986753");
+         Retract("Staff");
+}
+
+rule Students "Grants students access to the studios and dungeons" {
+     when
+		member.HasCardNumber(6000001)
+	 then
+         permissions.Grant("Studio *", "Dungeon *", "controller:Dungeons");
+         Retract("Students");
+}
+
+rule Prefects "Grants prefects access to the studios and kitchen" {
+     when
+		member.HasCardNumber(6000002)
+	 then
+         permissions.Grant("/^Studio [0-9]+$/:29", "/^Kitchen/", "/^Studio [/");
+         Retract("Prefects");
+}
+// *** END GRULES ***
+`), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	vocabulary := Vocabulary{
+		Doors: []string{"Great Hall", "Gryffindor", "Studio 1", "Studio 2"},
+		Controllers: Controllers{
+			405419896: controllers[405419896],
+		},
+	}
+
+	if warnings := rules.Lint(vocabulary); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Incorrect lint warnings\n   expected:%v\n   got:     %v", expected, warnings)
+	}
+}
@@ -0,0 +1,124 @@
+package acl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// Validity defines the default start and end dates assigned to a card before the rules
+// are evaluated. The rules can (and usually do) override the defaults e.g. with the member
+// 'registered' and 'expires' dates.
+//
+// The default start date is always the start of the current year - the policy determines
+// the default end date:
+//
+//   - end-of-year:        valid until the end of the current year (legacy behaviour)
+//   - rolling:<N>         valid until N days from today (inclusive) i.e. rolling:30 generated
+//     on 2026-01-01 has end date 2026-01-31 and rolling:0 is valid for today only
+//   - end-of-membership:  valid until the membership 'expires' date (end of year if not set)
+//   - <YYYY-MM-DD>:       valid until a fixed date
+//   - far-future:         valid until 2099-12-31, the last date supported by the controllers
+//
+// End dates are inclusive i.e. a card is valid up to and including the end date.
+type Validity struct {
+	Policy Policy
+	Days   int
+	Date   core.Date
+}
+
+type Policy int
+
+const (
+	EndOfYear Policy = iota
+	Rolling
+	EndOfMembership
+	FixedDate
+	FarFuture
+)
+
+func (p Policy) String() string {
+	if names := [...]string{"end-of-year", "rolling", "end-of-membership", "fixed", "far-future"}; int(p) >= 0 && int(p) < len(names) {
+		return names[p]
+	}
+
+	return fmt.Sprintf("unknown (%d)", int(p))
+}
+
+// The UT0311-L0x controllers store the year as two BCD digits.
+var farFuture = core.ToDate(2099, time.December, 31)
+
+func ParseValidity(s string) (Validity, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+
+	switch {
+	case v == "" || v == "end-of-year":
+		return Validity{Policy: EndOfYear}, nil
+
+	case v == "end-of-membership" || v == "membership":
+		return Validity{Policy: EndOfMembership}, nil
+
+	case v == "far-future":
+		return Validity{Policy: FarFuture}, nil
+	}
+
+	if match := regexp.MustCompile(`^rolling:\s*([0-9]+)$`).FindStringSubmatch(v); match != nil {
+		if days, err := strconv.Atoi(match[1]); err != nil {
+			return Validity{}, fmt.Errorf("invalid rolling validity period '%v' (%v)", s, err)
+		} else if days < 1 {
+			return Validity{}, fmt.Errorf("invalid rolling validity period '%v'", s)
+		} else {
+			return Validity{Policy: Rolling, Days: days}, nil
+		}
+	}
+
+	if date, err := core.ParseDate(strings.TrimPrefix(v, "fixed:")); err == nil {
+		return Validity{Policy: FixedDate, Date: date}, nil
+	}
+
+	return Validity{}, fmt.Errorf("invalid default validity '%v'", s)
+}
+
+func (v Validity) String() string {
+	switch v.Policy {
+	case Rolling:
+		return fmt.Sprintf("rolling:%v", v.Days)
+
+	case FixedDate:
+		return fmt.Sprintf("%v", v.Date)
+
+	default:
+		return fmt.Sprintf("%v", v.Policy)
+	}
+}
+
+// Returns the default start and end dates for a member, as of the clock date.
+func (v Validity) dates(m types.Member, clock types.Clock) (core.Date, core.Date) {
+	start := startOfYear(clock)
+
+	switch v.Policy {
+	case Rolling:
+		return start, plusDays(today(clock), v.Days)
+
+	case EndOfMembership:
+		if !m.Expires.IsZero() {
+			return start, m.Expires
+		}
+
+	case FixedDate:
+		if !v.Date.IsZero() {
+			return start, v.Date
+		}
+
+	case FarFuture:
+		return start, farFuture
+	}
+
+	return start, endOfYear(clock)
+}
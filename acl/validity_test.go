@@ -0,0 +1,148 @@
+package acl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+func TestParseValidity(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Validity
+	}{
+		{"", Validity{Policy: EndOfYear}},
+		{"end-of-year", Validity{Policy: EndOfYear}},
+		{"  End-Of-Year ", Validity{Policy: EndOfYear}},
+		{"rolling:30", Validity{Policy: Rolling, Days: 30}},
+		{"rolling: 7", Validity{Policy: Rolling, Days: 7}},
+		{"end-of-membership", Validity{Policy: EndOfMembership}},
+		{"membership", Validity{Policy: EndOfMembership}},
+		{"2026-06-30", Validity{Policy: FixedDate, Date: core.MustParseDate("2026-06-30")}},
+		{"fixed:2026-06-30", Validity{Policy: FixedDate, Date: core.MustParseDate("2026-06-30")}},
+		{"far-future", Validity{Policy: FarFuture}},
+	}
+
+	for _, test := range tests {
+		v, err := ParseValidity(test.s)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing '%v' (%v)", test.s, err)
+		}
+
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("Incorrectly parsed '%v' - expected:%v, got:%v", test.s, test.expected, v)
+		}
+	}
+}
+
+func TestParseInvalidValidity(t *testing.T) {
+	tests := []string{
+		"end-of-decade",
+		"rolling",
+		"rolling:0",
+		"rolling:-7",
+		"2026-02-30",
+	}
+
+	for _, s := range tests {
+		if _, err := ParseValidity(s); err == nil {
+			t.Errorf("Expected error parsing '%v', got:%v", s, err)
+		}
+	}
+}
+
+func TestValidityDates(t *testing.T) {
+	member := types.Member{
+		Name:    "Harry Potter",
+		Expires: core.MustParseDate("2021-06-30"),
+	}
+
+	tests := []struct {
+		validity Validity
+		member   types.Member
+		end      core.Date
+	}{
+		{Validity{Policy: EndOfYear}, member, endOfYear(nil)},
+		{Validity{Policy: Rolling, Days: 30}, member, plusDays(today(nil), 30)},
+		{Validity{Policy: EndOfMembership}, member, core.MustParseDate("2021-06-30")},
+		{Validity{Policy: EndOfMembership}, types.Member{}, endOfYear(nil)},
+		{Validity{Policy: FixedDate, Date: core.MustParseDate("2026-03-31")}, member, core.MustParseDate("2026-03-31")},
+		{Validity{Policy: FarFuture}, member, core.MustParseDate("2099-12-31")},
+	}
+
+	for _, test := range tests {
+		start, end := test.validity.dates(test.member, nil)
+
+		if !start.Equals(startOfYear(nil)) {
+			t.Errorf("%v: incorrect start date - expected:%v, got:%v", test.validity, startOfYear(nil), start)
+		}
+
+		if !end.Equals(test.end) {
+			t.Errorf("%v: incorrect end date - expected:%v, got:%v", test.validity, test.end, end)
+		}
+	}
+}
+
+func TestRollingValidityBoundary(t *testing.T) {
+	tests := []struct {
+		today core.Date
+		days  int
+		end   core.Date
+	}{
+		{core.ToDate(2026, time.January, 1), 0, core.ToDate(2026, time.January, 1)},
+		{core.ToDate(2026, time.January, 1), 1, core.ToDate(2026, time.January, 2)},
+		{core.ToDate(2026, time.January, 1), 30, core.ToDate(2026, time.January, 31)},
+		{core.ToDate(2026, time.January, 31), 1, core.ToDate(2026, time.February, 1)},
+		{core.ToDate(2028, time.February, 1), 28, core.ToDate(2028, time.February, 29)},
+		{core.ToDate(2026, time.December, 31), 1, core.ToDate(2027, time.January, 1)},
+	}
+
+	for _, test := range tests {
+		validity := Validity{Policy: Rolling, Days: test.days}
+
+		if _, end := validity.dates(types.Member{}, types.AsOf(test.today)); !end.Equals(test.end) {
+			t.Errorf("%v as of %v: incorrect end date - expected:%v, got:%v", validity, test.today, test.end, end)
+		}
+	}
+}
+
+func TestPolicyString(t *testing.T) {
+	tests := map[Policy]string{
+		EndOfYear:  "end-of-year",
+		Rolling:    "rolling",
+		FarFuture:  "far-future",
+		Policy(-1): "unknown (-1)",
+		Policy(9):  "unknown (9)",
+	}
+
+	for p, expected := range tests {
+		if s := p.String(); s != expected {
+			t.Errorf("Incorrect policy string - expected:%v, got:%v", expected, s)
+		}
+	}
+}
+
+func TestPlusDays(t *testing.T) {
+	tests := []struct {
+		date     core.Date
+		days     int
+		expected core.Date
+	}{
+		{core.ToDate(2021, time.June, 30), 0, core.ToDate(2021, time.June, 30)},
+		{core.ToDate(2021, time.June, 30), 14, core.ToDate(2021, time.July, 14)},
+		{core.ToDate(2021, time.December, 31), 365, core.ToDate(2022, time.December, 31)},
+		{core.ToDate(2021, time.January, 1), -1, core.ToDate(2020, time.December, 31)},
+		{core.ToDate(2024, time.February, 28), 1, core.ToDate(2024, time.February, 29)},
+		{core.ToDate(2023, time.February, 28), 1, core.ToDate(2023, time.March, 1)},
+	}
+
+	for _, test := range tests {
+		if d := plusDays(test.date, test.days); !d.Equals(test.expected) {
+			t.Errorf("plusDays(%v,%v) - expected:%v, got:%v", test.date, test.days, test.expected, d)
+		}
+	}
+}
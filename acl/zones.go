@@ -0,0 +1,45 @@
+package acl
+
+import (
+	"slices"
+)
+
+// Zones are named groups of doors that can be granted or revoked as a unit e.g.
+// permissions.Grant("zone:Workshop") grants access to all the doors in the 'Workshop' zone.
+type Zones map[string][]string
+
+// Returns true if the zone includes the door (zone and door names are normalised).
+func (z Zones) contains(zone string, door string) bool {
+	for k, doors := range z {
+		if normalise(k) == normalise(zone) {
+			return slices.ContainsFunc(doors, func(d string) bool { return normalise(d) == normalise(door) })
+		}
+	}
+
+	return false
+}
+
+// Returns true if the zone is defined.
+func (z Zones) defined(zone string) bool {
+	for k := range z {
+		if normalise(k) == normalise(zone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the zones that include a door.
+func (z Zones) Lookup(door string) []string {
+	zones := []string{}
+	for k := range z {
+		if z.contains(k, door) {
+			zones = append(zones, k)
+		}
+	}
+
+	slices.Sort(zones)
+
+	return zones
+}
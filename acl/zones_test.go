@@ -0,0 +1,98 @@
+package acl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var zones = Zones{
+	"Workshop":  []string{"Lathe", "Bandsaw", "Welding Bay"},
+	"Woodshop":  []string{"Bandsaw", "Router Table"},
+	"Hogsmeade": []string{"Three Broomsticks"},
+}
+
+var zoned = `
+// *** GRULES ***
+rule Students "Grants students access to the workshop" {
+     when
+		member.HasCardNumber(6000001)
+	 then
+         permissions.Grant("zone:Workshop", "zone:Woodshop:29", "Lathe:30");
+         permissions.Revoke("Welding Bay");
+         Retract("Students");
+}
+
+rule Staff "Grants staff access to all doors except the Woodshop" {
+     when
+		member.HasCardNumber(1000001)
+	 then
+         permissions.Grant("*");
+         permissions.Revoke("zone:Woodshop");
+         Retract("Staff");
+}
+// *** END GRULES ***
+`
+
+func TestZones(t *testing.T) {
+	members := types.Members{
+		Members: []types.Member{dumbledore, harry},
+	}
+
+	doors := []string{"Bandsaw", "Lathe", "Router Table", "Welding Bay"}
+
+	expected := [][]string{
+		{"1000001", "N", "Y", "N", "Y"},
+		{"6000001", "29", "30", "29", "N"},
+	}
+
+	rules, err := NewRules([]byte(zoned), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	rules.SetZones(zones)
+
+	acl, err := rules.MakeACL(members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if rows := permissions(acl); !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Incorrect ACL\n   expected:%v\n   got:     %v", expected, rows)
+	}
+}
+
+func TestZonesLookup(t *testing.T) {
+	expected := []string{"Woodshop", "Workshop"}
+
+	if list := zones.Lookup("band saw"); !reflect.DeepEqual(list, expected) {
+		t.Errorf("Incorrect zones\n   expected:%v\n   got:     %v", expected, list)
+	}
+}
+
+func TestZonesLint(t *testing.T) {
+	expected := []string{
+		"zone Hogsmeade: unknown door 'Three Broomsticks'",
+		"rule Staff: unknown zone 'Woodshop'",
+		"rule Students: unknown zone 'Woodshop'",
+	}
+
+	rules, err := NewRules([]byte(zoned), false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	vocabulary := Vocabulary{
+		Doors: []string{"Bandsaw", "Lathe", "Router Table", "Welding Bay"},
+		Zones: Zones{
+			"Workshop":  zones["Workshop"],
+			"Hogsmeade": zones["Hogsmeade"],
+		},
+	}
+
+	if warnings := rules.Lint(vocabulary); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Incorrect lint warnings\n   expected:%v\n   got:     %v", expected, warnings)
+	}
+}
@@ -20,6 +20,12 @@ var cli = []uhppoted.Command{
 	&commands.GetACLCmd,
 	&commands.CompareACLCmd,
 	&commands.LoadACLCmd,
+	&commands.TestRulesCmd,
+	&commands.ExplainCmd,
+	&commands.LintRulesCmd,
+	&commands.ExpiringCmd,
+	&commands.HistoryCmd,
+	&commands.RollbackCmd,
 
 	&uhppoted.Version{
 		Application: commands.APP,
@@ -0,0 +1,289 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// auditRecord is the audit store entry for a load-acl run, with the version information for the
+// members, rules and ACL and the card changes made on each controller. The audit store is an
+// append-only JSON lines file in the workdir (.wild-apricot/<account ID>.audit.jsonl). Rollbacks
+// are recorded with the ID of the reloaded snapshot.
+type auditRecord struct {
+	versionInfo
+	Rollback string        `json:"rollback,omitempty"`
+	Changes  []auditChange `json:"changes"`
+}
+
+// auditChange is a card added, updated or deleted (or that failed to load) on a controller, with
+// the doors on the controller to which the card has access after the change and the doors to which
+// the card had access before the change (for updated and deleted cards).
+type auditChange struct {
+	Controller uint32   `json:"controller"`
+	CardNumber uint32   `json:"card-number"`
+	MemberID   uint32   `json:"member-id,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Action     string   `json:"action"`
+	Doors      []string `json:"doors,omitempty"`
+	Previous   []string `json:"previous,omitempty"`
+}
+
+// auditQuery selects the audit history entries by card number, member (ID or name), door and
+// date range. Blank/zero fields match all entries.
+type auditQuery struct {
+	CardNumber uint32
+	Member     string
+	Door       string
+	From       core.Date
+	To         core.Date
+}
+
+// auditEntry is a single card change in the audit history, with the rules revision (or hash if
+// the rules do not have a revision).
+type auditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	auditChange
+	Rules string `json:"rules,omitempty"`
+}
+
+func auditFile(workdir string, accountID uint32) string {
+	return filepath.Join(workdir, ".wild-apricot", fmt.Sprintf("%v.audit.jsonl", accountID))
+}
+
+// Returns the audit changes for the per-controller load-acl report, with the card holder name and
+// member ID from the members list, the doors from the ACL table and the previous doors from the ACL
+// on the controllers before the load.
+func auditChanges(rpt map[uint32]lib.Report, table *lib.Table, previous lib.ACL, devices []uhppote.Device, members types.Members) []auditChange {
+	index := map[uint32]types.Member{}
+	for _, m := range members.Members {
+		if m.CardNumber != nil {
+			index[uint32(*m.CardNumber)] = m
+		}
+	}
+
+	columns := map[string]int{}
+	for i, h := range table.Header {
+		columns[normalise(h)] = i
+	}
+
+	rows := map[uint32][]string{}
+	for _, row := range table.Records {
+		if ix, ok := columns["cardnumber"]; ok && ix < len(row) {
+			if card, err := strconv.ParseUint(strings.TrimSpace(row[ix]), 10, 32); err == nil {
+				rows[uint32(card)] = row
+			}
+		}
+	}
+
+	controllers := map[uint32][]string{}
+	for _, d := range devices {
+		controllers[d.DeviceID] = d.Doors
+	}
+
+	doors := func(controller, card uint32) []string {
+		list := []string{}
+		if row, ok := rows[card]; ok {
+			for _, door := range controllers[controller] {
+				if door = clean(door); door == "" {
+					continue
+				} else if ix, ok := columns[normalise(door)]; ok && ix < len(row) && row[ix] != "N" && row[ix] != "" {
+					list = append(list, door)
+				}
+			}
+		}
+
+		return list
+	}
+
+	previously := func(controller, card uint32) []string {
+		list := []string{}
+		if c, ok := previous[controller][card]; ok {
+			for door := uint8(1); door <= 4; door++ {
+				if c.Doors[door] != 0 {
+					list = append(list, doorName(controllers[controller], door))
+				}
+			}
+		}
+
+		return list
+	}
+
+	changes := []auditChange{}
+	for _, controller := range slices.Sorted(maps.Keys(rpt)) {
+		r := rpt[controller]
+		actions := []struct {
+			cards  []uint32
+			action string
+		}{
+			{r.Added, "added"},
+			{r.Updated, "updated"},
+			{r.Deleted, "deleted"},
+			{r.Failed, "failed"},
+			{r.Errored, "error"},
+		}
+
+		for _, a := range actions {
+			for _, card := range a.cards {
+				change := auditChange{
+					Controller: controller,
+					CardNumber: card,
+					Action:     a.action,
+				}
+
+				if m, ok := index[card]; ok {
+					change.MemberID = m.ID
+					change.Name = m.Name
+				}
+
+				if a.action != "deleted" {
+					change.Doors = doors(controller, card)
+				}
+
+				if a.action == "updated" || a.action == "deleted" {
+					change.Previous = previously(controller, card)
+				}
+
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	return changes
+}
+
+// Appends a load-acl run to the audit store.
+func appendAudit(workdir string, record auditRecord) error {
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file := auditFile(workdir, record.AccountID)
+	if err := os.MkdirAll(filepath.Dir(file), 0770); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(f, "%s\n", bytes); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Reads the audit store for an account. Returns an empty list if the audit store does not exist.
+func readAudit(workdir string, accountID uint32) ([]auditRecord, error) {
+	file := auditFile(workdir, accountID)
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return []auditRecord{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	records := []auditRecord{}
+	decoder := json.NewDecoder(f)
+	for {
+		record := auditRecord{}
+		if err := decoder.Decode(&record); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid audit store %v (%v)", file, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// Returns the card changes in the audit records that match the query, in chronological order.
+func (q auditQuery) find(records []auditRecord) []auditEntry {
+	entries := []auditEntry{}
+
+	for _, r := range records {
+		if r.Timestamp == nil || !q.dated(*r.Timestamp) {
+			continue
+		}
+
+		rules := r.Revisions.Rules
+		if rules == "" {
+			rules = r.Hashes.Rules
+		}
+
+		for _, c := range r.Changes {
+			if q.matches(c) {
+				entries = append(entries, auditEntry{
+					Timestamp:   *r.Timestamp,
+					auditChange: c,
+					Rules:       rules,
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(entries, func(p, q auditEntry) int { return p.Timestamp.Compare(q.Timestamp) })
+
+	return entries
+}
+
+func (q auditQuery) dated(t time.Time) bool {
+	date := core.ToDate(t.Year(), t.Month(), t.Day())
+
+	if !q.From.IsZero() && date.Before(q.From) {
+		return false
+	}
+
+	if !q.To.IsZero() && date.After(q.To) {
+		return false
+	}
+
+	return true
+}
+
+func (q auditQuery) matches(c auditChange) bool {
+	if q.CardNumber != 0 && c.CardNumber != q.CardNumber {
+		return false
+	}
+
+	if member := strings.TrimSpace(q.Member); member != "" {
+		if id, err := strconv.ParseUint(member, 10, 32); err == nil {
+			if c.MemberID != uint32(id) {
+				return false
+			}
+		} else if normalise(c.Name) != normalise(member) {
+			return false
+		}
+	}
+
+	if door := strings.TrimSpace(q.Door); door != "" {
+		f := func(d string) bool { return normalise(d) == normalise(door) }
+		if !slices.ContainsFunc(c.Doors, f) && !slices.ContainsFunc(c.Previous, f) {
+			return false
+		}
+	}
+
+	return true
+}
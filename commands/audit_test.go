@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+)
+
+func TestAuditChanges(t *testing.T) {
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	table := lib.Table{
+		Header: []string{"Card Number", "From", "To", "Great Hall", "Gryffindor", "Dungeon"},
+		Records: [][]string{
+			{"1000001", "2026-01-01", "2026-12-31", "Y", "Y", "Y"},
+			{"6000001", "2026-01-01", "2026-12-31", "Y", "29", "N"},
+		},
+	}
+
+	devices := []uhppote.Device{
+		{DeviceID: 405419896, Doors: []string{"Great Hall", "Gryffindor", "", ""}},
+		{DeviceID: 303986753, Doors: []string{"Dungeon", "Kitchen", "", ""}},
+	}
+
+	card := func(number uint32, doors ...uint8) core.Card {
+		return core.Card{
+			CardNumber: number,
+			From:       core.MustParseDate("2026-01-01"),
+			To:         core.MustParseDate("2026-12-31"),
+			Doors:      map[uint8]uint8{1: doors[0], 2: doors[1], 3: doors[2], 4: doors[3]},
+		}
+	}
+
+	previous := lib.ACL{
+		405419896: {
+			2000001: card(2000001, 1, 1, 0, 0),
+		},
+		303986753: {
+			1000001: card(1000001, 1, 1, 0, 0),
+		},
+	}
+
+	rpt := map[uint32]lib.Report{
+		405419896: {Added: []uint32{6000001}, Deleted: []uint32{2000001}},
+		303986753: {Updated: []uint32{1000001}},
+	}
+
+	expected := []auditChange{
+		{Controller: 303986753, CardNumber: 1000001, MemberID: 1, Name: "Albus Dumbledore", Action: "updated", Doors: []string{"Dungeon"}, Previous: []string{"Dungeon", "Kitchen"}},
+		{Controller: 405419896, CardNumber: 6000001, MemberID: 2, Name: "Harry Potter", Action: "added", Doors: []string{"Great Hall", "Gryffindor"}},
+		{Controller: 405419896, CardNumber: 2000001, MemberID: 4, Name: "Tom Riddle", Action: "deleted", Previous: []string{"Great Hall", "Gryffindor"}},
+	}
+
+	if changes := auditChanges(rpt, &table, previous, devices, *members); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Incorrect audit changes\n   expected:%v\n   got:     %v", expected, changes)
+	}
+}
+
+func TestAuditHistory(t *testing.T) {
+	workdir := t.TempDir()
+
+	record := func(timestamp string, changes ...auditChange) auditRecord {
+		ts, _ := time.ParseInLocation("2006-01-02 15:04:05", timestamp, time.Local)
+		r := auditRecord{
+			Changes: changes,
+		}
+
+		r.AccountID = 12345
+		r.Timestamp = &ts
+		r.Hashes.Rules = "4c8a2d6e0f7b13579bdf2468ace013579bdf2468ace013579bdf2468ace01357"
+
+		return r
+	}
+
+	harry := auditChange{Controller: 405419896, CardNumber: 6000001, MemberID: 2, Name: "Harry Potter", Action: "added", Doors: []string{"Great Hall", "Gryffindor"}}
+	hermione := auditChange{Controller: 405419896, CardNumber: 6000002, MemberID: 3, Name: "Hermione Granger", Action: "added", Doors: []string{"Great Hall"}}
+	tom := auditChange{Controller: 303986753, CardNumber: 2000001, MemberID: 4, Name: "Tom Riddle", Action: "deleted", Previous: []string{"Dungeon"}}
+
+	for _, r := range []auditRecord{
+		record("2026-01-15 09:30:00", harry, hermione),
+		record("2026-02-01 09:30:00"),
+		record("2026-02-15 09:30:00", tom),
+	} {
+		if err := appendAudit(workdir, r); err != nil {
+			t.Fatalf("Unexpected error (%v)", err)
+		}
+	}
+
+	records, err := readAudit(workdir, 12345)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	} else if len(records) != 3 {
+		t.Fatalf("Incorrect number of audit records - expected:%v, got:%v", 3, len(records))
+	}
+
+	tests := []struct {
+		query    auditQuery
+		expected []uint32
+	}{
+		{auditQuery{}, []uint32{6000001, 6000002, 2000001}},
+		{auditQuery{CardNumber: 6000002}, []uint32{6000002}},
+		{auditQuery{Member: "4"}, []uint32{2000001}},
+		{auditQuery{Member: "harry potter"}, []uint32{6000001}},
+		{auditQuery{Door: "gryffindor"}, []uint32{6000001}},
+		{auditQuery{Door: "dungeon"}, []uint32{2000001}},
+		{auditQuery{From: core.MustParseDate("2026-02-01")}, []uint32{2000001}},
+		{auditQuery{To: core.MustParseDate("2026-01-15")}, []uint32{6000001, 6000002}},
+		{auditQuery{From: core.MustParseDate("2026-01-16"), To: core.MustParseDate("2026-02-14")}, []uint32{}},
+	}
+
+	for _, test := range tests {
+		cards := []uint32{}
+		for _, e := range test.query.find(records) {
+			cards = append(cards, e.CardNumber)
+		}
+
+		if !reflect.DeepEqual(cards, test.expected) {
+			t.Errorf("Incorrect audit history for %+v\n   expected:%v\n   got:     %v", test.query, test.expected, cards)
+		}
+	}
+
+	if records, err := readAudit(workdir, 54321); err != nil {
+		t.Errorf("Unexpected error (%v)", err)
+	} else if len(records) != 0 {
+		t.Errorf("Expected empty audit history for unknown account, got %v", records)
+	}
+}
@@ -98,6 +98,14 @@ func clean(v string) string {
 	return strings.TrimSpace(v)
 }
 
+func memberID(id uint32) string {
+	if id == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%v", id)
+}
+
 func debugf(format string, args ...any) {
 	log.Debugf(format, args...)
 }
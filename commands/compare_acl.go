@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"maps"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -19,26 +20,29 @@ import (
 )
 
 var CompareACLCmd = CompareACL{
-	workdir:     DEFAULT_WORKDIR,
-	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
-	rules:       filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
-	withPIN:     false,
-	summary:     false,
-	strict:      false,
-	lockfile:    "",
-	debug:       false,
+	workdir:      DEFAULT_WORKDIR,
+	credentials:  filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	rules:        filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
+	withPIN:      false,
+	withMemberID: false,
+	summary:      false,
+	strict:       false,
+	lockfile:     "",
+	debug:        false,
 }
 
 type CompareACL struct {
-	workdir     string
-	credentials string
-	rules       string
-	file        string
-	withPIN     bool
-	summary     bool
-	strict      bool
-	lockfile    string
-	debug       bool
+	workdir      string
+	credentials  string
+	rules        string
+	file         string
+	withPIN      bool
+	withMemberID bool
+	summary      bool
+	strict       bool
+	asOf         string
+	lockfile     string
+	debug        bool
 }
 
 func (cmd *CompareACL) Name() string {
@@ -55,7 +59,7 @@ func (cmd *CompareACL) Usage() string {
 
 func (cmd *CompareACL) Help() {
 	fmt.Println()
-	fmt.Printf("  Usage: %s [--debug] [--config <file>] compare-acl [--credentials <file>] [--rules <url>] [--with-pin] [--summary] [--report <file>]\n", APP)
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] compare-acl [--credentials <file>] [--rules <url>] [--with-pin] [--with-member-id] [--summary] [--as-of <date>] [--report <file>]\n", APP)
 	fmt.Println()
 	fmt.Println("  Downloads an access control list from a Wild Apricot member database, applies the ACL rules and stores the generated")
 	fmt.Println("  access control list to a TSV file")
@@ -77,11 +81,13 @@ func (cmd *CompareACL) FlagSet() *flag.FlagSet {
 
 	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
 	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file. Defaults to "+cmd.credentials)
-	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file. Support file path, HTTP and HTTPS. Defaults to "+cmd.rules)
+	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file (.grl) policy file (.yaml or .json) or CEL rules file (.cel). Support file path, HTTP, HTTPS and git+https/git+file and comma separated lists of URIs. Defaults to "+cmd.rules)
 	flagset.BoolVar(&cmd.withPIN, "with-pin", cmd.withPIN, "Include card keypad PIN code ACL comparison")
+	flagset.BoolVar(&cmd.withMemberID, "with-member-id", cmd.withMemberID, "Include the Wild Apricot member ID in the compare report")
 	flagset.BoolVar(&cmd.summary, "summary", cmd.summary, "Report only a summary of the comparison. Defaults to "+fmt.Sprintf("%v", cmd.summary))
 	flagset.StringVar(&cmd.file, "report", cmd.file, "Report file name. Defaults to stdout")
-	flagset.BoolVar(&cmd.strict, "strict", cmd.strict, "Fails with an error if the members list contains duplicate card numbers")
+	flagset.BoolVar(&cmd.strict, "strict", cmd.strict, "Fails with an error if the members list contains duplicate card numbers or the ACL references missing or mismatched time profiles")
+	flagset.StringVar(&cmd.asOf, "as-of", cmd.asOf, "Generates the ACL as of a date (YYYY-MM-DD) e.g. to preview the ACL after a membership renewal date. Defaults to today")
 	flagset.StringVar(&cmd.lockfile, "lockfile", cmd.lockfile, fmt.Sprintf("Filepath for lock file. Defaults to %v", lockfile))
 
 	return flagset
@@ -101,6 +107,11 @@ func (cmd *CompareACL) Execute(args ...any) error {
 		return fmt.Errorf("invalid rules file")
 	}
 
+	clock, err := getClock(cmd.asOf)
+	if err != nil {
+		return err
+	}
+
 	// ... locked?
 	lockFile := config.Lockfile{
 		File:   filepath.Join(cmd.workdir, ".wild-apricot", "uhppoted-app-wild-apricot.lock"),
@@ -129,16 +140,20 @@ func (cmd *CompareACL) Execute(args ...any) error {
 		return fmt.Errorf("could not load configuration (%v)", err)
 	}
 
-	credentials, err := getCredentials(cmd.credentials)
+	settings, err := getSettings(options.Config, conf)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not load configuration (%v)", err)
 	}
 
-	rules, err := getRules(cmd.rules, cmd.workdir, cmd.debug)
+	credentials, err := getCredentials(cmd.credentials)
 	if err != nil {
 		return err
 	}
 
+	if clock != nil {
+		infof("Generating ACL as of %v", clock.Today())
+	}
+
 	members, err := getMembers(conf, credentials)
 	if err != nil {
 		return err
@@ -152,12 +167,45 @@ func (cmd *CompareACL) Execute(args ...any) error {
 		}
 	}
 
-	// ... make ACL
-	doors, err := getDoors(conf)
+	// ... make and compare the ACL for each site
+	u, devices := getDevices(conf, cmd.debug)
+
+	sites, err := getSites(settings.Sites, cmd.rules, devices)
 	if err != nil {
 		return err
 	}
 
+	diff := lib.SystemDiff{}
+	for _, site := range sites {
+		d, err := cmd.compareSite(conf, settings, site, *members, clock, u)
+		if err != nil {
+			return err
+		}
+
+		maps.Copy(diff, *d)
+	}
+
+	// ... summary?
+	if cmd.summary {
+		return cmd.summarize(diff)
+	}
+
+	// ... detail report
+	return cmd.report(*members, diff)
+}
+
+// Generates the ACL for a site from the site rules file and compares it with the cards on the
+// site controllers.
+func (cmd *CompareACL) compareSite(conf *config.Config, settings *settings, site site, members types.Members, clock types.Clock, u uhppote.IUHPPOTE) (*lib.SystemDiff, error) {
+	rules, doors, err := getSiteRules(conf, settings, site, cmd.workdir, cmd.debug)
+	if err != nil {
+		return nil, err
+	}
+
+	if clock != nil {
+		rules.SetClock(clock)
+	}
+
 	if cmd.debug {
 		fmt.Printf("DOORS:\n")
 		for _, d := range doors {
@@ -166,43 +214,34 @@ func (cmd *CompareACL) Execute(args ...any) error {
 		fmt.Println()
 	}
 
-	makeACL := func(members types.Members, doors []string) (*acl.ACL, error) {
-		if cmd.withPIN {
-			return rules.MakeACLWithPIN(members, doors)
-		} else {
-			return rules.MakeACL(members, doors)
-		}
+	getPreviousACL(rules, settings, u, site.Devices)
+
+	var ACL *acl.ACL
+	if cmd.withPIN {
+		ACL, err = rules.MakeACLWithPIN(members, doors)
+	} else {
+		ACL, err = rules.MakeACL(members, doors)
 	}
 
-	acl, err := makeACL(*members, doors)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	logFailures(ACL, settings.evaluation.OnError)
+
 	if cmd.debug {
 		if cmd.withPIN {
-			fmt.Printf("ACL:\n%s\n", string(acl.AsTableWithPIN().MarshalTextIndent("  ", " ")))
+			fmt.Printf("ACL:\n%s\n", string(ACL.AsTableWithPIN().MarshalTextIndent("  ", " ")))
 		} else {
-			fmt.Printf("ACL:\n%s\n", string(acl.AsTable().MarshalTextIndent("  ", " ")))
+			fmt.Printf("ACL:\n%s\n", string(ACL.AsTable().MarshalTextIndent("  ", " ")))
 		}
 	}
 
-	// ... compare
-
-	u, devices := getDevices(conf, cmd.debug)
-
-	diff, err := cmd.compare(u, devices, acl)
-	if err != nil {
-		return err
-	}
-
-	// ... summary?
-	if cmd.summary {
-		return cmd.summarize(*diff)
+	if err := checkTimeProfiles(u, site.Devices, ACL.Profiles(), settings.schedules, clock, cmd.strict, false); err != nil {
+		return nil, err
 	}
 
-	// ... detail report
-	return cmd.report(*members, *diff)
+	return cmd.compare(u, site.Devices, ACL)
 }
 
 func (cmd *CompareACL) compare(u uhppote.IUHPPOTE, devices []uhppote.Device, cards *acl.ACL) (*lib.SystemDiff, error) {
@@ -255,7 +294,7 @@ func (cmd *CompareACL) summarize(diff lib.SystemDiff) error {
 
 	if cmd.file == "" {
 		fmt.Println()
-		fmt.Printf("  ACL Compare Report %s\n", time.Now().Format("2006-01-02 15:03:04"))
+		fmt.Printf("  %s\n", cmd.title())
 		fmt.Println()
 		fmt.Printf("%v\n", string(rpt.MarshalTextIndent("  ", " ")))
 		fmt.Println()
@@ -278,18 +317,18 @@ func (cmd *CompareACL) summarize(diff lib.SystemDiff) error {
 }
 
 func (cmd *CompareACL) report(members types.Members, diff lib.SystemDiff) error {
-	rpt := detail(members, diff)
+	rpt := detail(members, diff, cmd.withMemberID)
 
 	if cmd.file == "" {
 		if !diff.HasChanges() {
 			fmt.Println()
-			fmt.Printf("  ACL Compare Report %s\n", time.Now().Format("2006-01-02 15:03:04"))
+			fmt.Printf("  %s\n", cmd.title())
 			fmt.Println()
 			fmt.Printf("%v\n", "  NO DIFFERENCES")
 			fmt.Println()
 		} else {
 			fmt.Println()
-			fmt.Printf("  ACL Compare Report %s\n", time.Now().Format("2006-01-02 15:03:04"))
+			fmt.Printf("  %s\n", cmd.title())
 			fmt.Println()
 			fmt.Printf("%v\n", string(rpt.MarshalTextIndent("  ", " ")))
 			fmt.Println()
@@ -311,6 +350,16 @@ func (cmd *CompareACL) report(members types.Members, diff lib.SystemDiff) error
 	return nil
 }
 
+// Returns the console report title, with the --as-of date (if any).
+func (cmd *CompareACL) title() string {
+	title := fmt.Sprintf("ACL Compare Report %s", time.Now().Format("2006-01-02 15:03:04"))
+	if strings.TrimSpace(cmd.asOf) != "" {
+		title += fmt.Sprintf(" (as of %v)", strings.TrimSpace(cmd.asOf))
+	}
+
+	return title
+}
+
 func summarize(diff lib.SystemDiff) *lib.Table {
 	keys := []uint32{}
 	for k := range diff {
@@ -378,7 +427,7 @@ func summarize(diff lib.SystemDiff) *lib.Table {
 	return &table
 }
 
-func detail(members types.Members, diff lib.SystemDiff) *lib.Table {
+func detail(members types.Members, diff lib.SystemDiff, withMemberID bool) *lib.Table {
 	type card struct {
 		cardnumber uint32
 		action     string
@@ -416,9 +465,11 @@ func detail(members types.Members, diff lib.SystemDiff) *lib.Table {
 	}
 
 	names := map[uint32]string{}
+	ids := map[uint32]uint32{}
 	for _, v := range members.Members {
 		if v.CardNumber != nil {
 			names[uint32(*v.CardNumber)] = clean(v.Name)
+			ids[uint32(*v.CardNumber)] = v.ID
 		}
 	}
 
@@ -431,15 +482,29 @@ func detail(members types.Members, diff lib.SystemDiff) *lib.Table {
 
 	timestamp := time.Now().Format("2006-01-02 15:03:04")
 	header := []string{"Timestamp", "Name", "Card Number", "Action"}
+	if withMemberID {
+		header = []string{"Timestamp", "Name", "Member ID", "Card Number", "Action"}
+	}
+
 	data := [][]string{}
 	for _, k := range keys {
 		if card, ok := cards[k]; ok {
-			data = append(data, []string{
-				timestamp,
-				names[card.cardnumber],
-				fmt.Sprintf("%v", card.cardnumber),
-				card.action,
-			})
+			if withMemberID {
+				data = append(data, []string{
+					timestamp,
+					names[card.cardnumber],
+					memberID(ids[card.cardnumber]),
+					fmt.Sprintf("%v", card.cardnumber),
+					card.action,
+				})
+			} else {
+				data = append(data, []string{
+					timestamp,
+					names[card.cardnumber],
+					fmt.Sprintf("%v", card.cardnumber),
+					card.action,
+				})
+			}
 		}
 	}
 
@@ -8,6 +8,8 @@ import (
 
 	api "github.com/uhppoted/uhppoted-lib/acl"
 	"github.com/uhppoted/uhppoted-lib/config"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
 )
 
 type Doors []string
@@ -66,11 +68,17 @@ func getDoors(conf *config.Config) (Doors, error) {
 	return list, nil
 }
 
-func (doors *Doors) AsTable() *api.Table {
+// Returns the doors as a table, with the zones that include each door if any zones are
+// defined.
+func (doors *Doors) AsTable(zones acl.Zones) *api.Table {
 	header := []string{
 		"Door",
 	}
 
+	if len(zones) > 0 {
+		header = append(header, "Zones")
+	}
+
 	data := [][]string{}
 
 	if doors != nil {
@@ -79,6 +87,10 @@ func (doors *Doors) AsTable() *api.Table {
 				fmt.Sprintf("%v", d),
 			}
 
+			if len(zones) > 0 {
+				row = append(row, strings.Join(zones.Lookup(d), ", "))
+			}
+
 			data = append(data, row)
 		}
 	}
@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+	"github.com/uhppoted/uhppoted-lib/config"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/log"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var ExpiringCmd = Expiring{
+	workdir:     DEFAULT_WORKDIR,
+	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	rules:       filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
+	days:        30,
+	format:      "",
+	debug:       false,
+}
+
+type Expiring struct {
+	workdir     string
+	credentials string
+	rules       string
+	days        uint
+	asOf        string
+	format      string
+	file        string
+	debug       bool
+}
+
+// expiry is an entry in the 'expiring' report.
+type expiry struct {
+	Name       string    `json:"name"`
+	Email      string    `json:"email"`
+	Membership string    `json:"membership"`
+	MemberID   uint32    `json:"member-id"`
+	CardNumber uint32    `json:"card-number"`
+	Expires    core.Date `json:"expires"`
+	Days       int       `json:"days"`
+}
+
+func (cmd *Expiring) Name() string {
+	return "expiring"
+}
+
+func (cmd *Expiring) Description() string {
+	return "Lists the cards for which door access expires within the next N days"
+}
+
+func (cmd *Expiring) Usage() string {
+	return "--credentials <file> --rules <url> --days <days>"
+}
+
+func (cmd *Expiring) Help() {
+	fmt.Println()
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] expiring [--credentials <file>] [--rules <url>] [--days <days>] [--as-of <date>] [--format table|tsv|json] [--file <file>]\n", APP)
+	fmt.Println()
+	fmt.Println("  Generates the access control list from the Wild Apricot member database and the ACL rules and lists the")
+	fmt.Println("  cards with access to at least one door that expire (i.e. the card end date) within the next N days, with")
+	fmt.Println("  the member name, email and membership level")
+	fmt.Println()
+
+	helpOptions(cmd.FlagSet())
+
+	fmt.Println()
+	fmt.Println("  Examples:")
+	fmt.Println(`    uhppote-app-wild-apricot expiring --credentials ".credentials/wild-apricot.json" \`)
+	fmt.Println(`                                      --rules "wild-apricot.grl" \`)
+	fmt.Println(`                                      --days 30 \`)
+	fmt.Println(`                                      --file "expiring.tsv"`)
+	fmt.Println()
+}
+
+func (cmd *Expiring) FlagSet() *flag.FlagSet {
+	flagset := flag.NewFlagSet("expiring", flag.ExitOnError)
+
+	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
+	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file. Defaults to "+cmd.credentials)
+	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file (.grl) policy file (.yaml or .json) or CEL rules file (.cel). Support file path, HTTP, HTTPS and git+https/git+file and comma separated lists of URIs. Defaults to "+cmd.rules)
+	flagset.UintVar(&cmd.days, "days", cmd.days, fmt.Sprintf("Number of days from today for which to list expiring cards. Defaults to %v", cmd.days))
+	flagset.StringVar(&cmd.asOf, "as-of", cmd.asOf, "Lists the cards expiring after a date (YYYY-MM-DD) rather than today")
+	flagset.StringVar(&cmd.format, "format", cmd.format, "Output format (table, tsv or json). Defaults to the --file extension or 'table'")
+	flagset.StringVar(&cmd.file, "file", cmd.file, "Output file name. Defaults to stdout")
+
+	return flagset
+}
+
+func (cmd *Expiring) Execute(args ...any) error {
+	options := args[0].(*Options)
+
+	cmd.debug = options.Debug
+
+	log.SetDebug(options.Debug)
+
+	// ... check parameters
+	if strings.TrimSpace(cmd.credentials) == "" {
+		return fmt.Errorf("invalid credentials file")
+	}
+
+	if strings.TrimSpace(cmd.rules) == "" {
+		return fmt.Errorf("invalid rules file")
+	}
+
+	clock, err := getClock(cmd.asOf)
+	if err != nil {
+		return err
+	}
+
+	format, err := outputFormat(cmd.format, cmd.file)
+	if err != nil {
+		return err
+	}
+
+	// ... get config, members, rules and doors
+	conf := config.NewConfig()
+	if err := conf.Load(options.Config); err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	settings, err := getSettings(options.Config, conf)
+	if err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	credentials, err := getCredentials(cmd.credentials)
+	if err != nil {
+		return err
+	}
+
+	members, err := getMembers(conf, credentials)
+	if err != nil {
+		return err
+	}
+
+	// ... generate the ACL for each site and list expiring cards
+	_, devices := getDevices(conf, cmd.debug)
+
+	sites, err := getSites(settings.Sites, cmd.rules, devices)
+	if err != nil {
+		return err
+	}
+
+	ACLs := []*acl.ACL{}
+	for _, site := range sites {
+		rules, doors, err := getSiteRules(conf, settings, site, cmd.workdir, cmd.debug)
+		if err != nil {
+			return err
+		}
+
+		rules.SetClock(clock)
+
+		ACL, err := rules.MakeACL(*members, doors)
+		if err != nil {
+			return err
+		}
+
+		logFailures(ACL, settings.evaluation.OnError)
+
+		ACLs = append(ACLs, ACL)
+	}
+
+	list := expiring(ACLs, members, clock.Today(), int(cmd.days))
+
+	if err := output(list, expiryTable(list), format, cmd.file); err != nil {
+		return err
+	}
+
+	if cmd.file != "" {
+		infof("%v expiring cards saved to %s", len(list), cmd.file)
+	}
+
+	return nil
+}
+
+// Returns the cards with access to at least one door for which the end date is within the given
+// number of days after 'today', ordered by end date and name. A card in the ACLs for more than one
+// site is listed once, with the earliest end date.
+func expiring(ACLs []*acl.ACL, members *types.Members, today core.Date, days int) []expiry {
+	index := map[uint32]types.Member{}
+	for _, m := range members.Members {
+		if m.CardNumber != nil {
+			index[uint32(*m.CardNumber)] = m
+		}
+	}
+
+	cards := map[uint32]expiry{}
+	for _, ACL := range ACLs {
+		for _, card := range ACL.Cards() {
+			if len(card.Doors) == 0 || card.EndDate.IsZero() || card.EndDate.Before(today) {
+				continue
+			}
+
+			n := int(math.Round(time.Time(card.EndDate).Sub(time.Time(today)).Hours() / 24))
+			if n > days {
+				continue
+			}
+
+			if e, ok := cards[card.CardNumber]; ok && e.Days <= n {
+				continue
+			}
+
+			m := index[card.CardNumber]
+			cards[card.CardNumber] = expiry{
+				Name:       strings.TrimSpace(card.Name),
+				Email:      m.Email,
+				Membership: m.Membership.Name,
+				MemberID:   card.MemberID,
+				CardNumber: card.CardNumber,
+				Expires:    card.EndDate,
+				Days:       n,
+			}
+		}
+	}
+
+	list := slices.Collect(maps.Values(cards))
+
+	slices.SortStableFunc(list, func(p, q expiry) int {
+		if p.Days != q.Days {
+			return p.Days - q.Days
+		}
+
+		if p.Name != q.Name {
+			return strings.Compare(p.Name, q.Name)
+		}
+
+		return int(p.CardNumber) - int(q.CardNumber)
+	})
+
+	return list
+}
+
+func expiryTable(list []expiry) *lib.Table {
+	table := lib.Table{
+		Header:  []string{"Name", "Email", "Membership", "Member ID", "Card Number", "Expires", "Days"},
+		Records: [][]string{},
+	}
+
+	for _, e := range list {
+		table.Records = append(table.Records, []string{
+			e.Name,
+			e.Email,
+			e.Membership,
+			memberID(e.MemberID),
+			fmt.Sprintf("%v", e.CardNumber),
+			fmt.Sprintf("%v", e.Expires),
+			fmt.Sprintf("%v", e.Days),
+		})
+	}
+
+	return &table
+}
@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	core "github.com/uhppoted/uhppote-core/types"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+func TestExpiring(t *testing.T) {
+	cmd := TestRules{
+		rules: "test_rules.grl",
+		doors: "test_doors.txt",
+	}
+
+	rules, err := cmd.getRules(&settings{})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	doors, err := cmd.getDoors("")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	expires := map[string]string{
+		"Albus Dumbledore": "2026-09-01",
+		"Harry Potter":     "2026-07-15",
+		"Hermione Granger": "2026-07-10",
+		"Tom Riddle":       "2026-07-05",
+	}
+
+	for i, m := range members.Members {
+		members.Members[i].Expires = core.MustParseDate(expires[m.Name])
+	}
+
+	today := core.MustParseDate("2026-07-01")
+
+	rules.SetDefaultValidity(acl.Validity{Policy: acl.EndOfMembership})
+	rules.SetClock(types.AsOf(today))
+
+	ACL, err := rules.MakeACL(*members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	expected := []expiry{
+		{
+			Name:       "Harry Potter",
+			Email:      "harry@hogwarts.edu",
+			Membership: "Student",
+			MemberID:   2,
+			CardNumber: 6000001,
+			Expires:    core.MustParseDate("2026-07-15"),
+			Days:       14,
+		},
+	}
+
+	if list := expiring([]*acl.ACL{ACL}, members, today, 30); !reflect.DeepEqual(list, expected) {
+		t.Errorf("Incorrect expiring cards\n   expected:%v\n   got:     %v", expected, list)
+	}
+
+	if list := expiring([]*acl.ACL{ACL}, members, today, 7); len(list) != 0 {
+		t.Errorf("Incorrect expiring cards\n   expected:%v\n   got:     %v", []expiry{}, list)
+	}
+}
+
+func TestExpiringWithMultipleSites(t *testing.T) {
+	cmd := TestRules{
+		rules: "test_rules.grl",
+		doors: "test_doors.txt",
+	}
+
+	rules, err := cmd.getRules(&settings{})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	doors, err := cmd.getDoors("")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	today := core.MustParseDate("2026-07-01")
+
+	rules.SetDefaultValidity(acl.Validity{Policy: acl.EndOfMembership})
+	rules.SetClock(types.AsOf(today))
+
+	makeACL := func(expires map[string]string) *acl.ACL {
+		for i, m := range members.Members {
+			members.Members[i].Expires = core.MustParseDate(expires[m.Name])
+		}
+
+		ACL, err := rules.MakeACL(*members, doors)
+		if err != nil {
+			t.Fatalf("Unexpected error (%v)", err)
+		}
+
+		return ACL
+	}
+
+	ACLs := []*acl.ACL{
+		makeACL(map[string]string{
+			"Albus Dumbledore": "2026-09-01",
+			"Harry Potter":     "2026-07-15",
+			"Hermione Granger": "2026-07-10",
+			"Tom Riddle":       "2026-07-05",
+		}),
+		makeACL(map[string]string{
+			"Albus Dumbledore": "2026-09-01",
+			"Harry Potter":     "2026-07-08",
+			"Hermione Granger": "2026-07-10",
+			"Tom Riddle":       "2026-07-05",
+		}),
+	}
+
+	expected := []expiry{
+		{
+			Name:       "Harry Potter",
+			Email:      "harry@hogwarts.edu",
+			Membership: "Student",
+			MemberID:   2,
+			CardNumber: 6000001,
+			Expires:    core.MustParseDate("2026-07-08"),
+			Days:       7,
+		},
+	}
+
+	if list := expiring(ACLs, members, today, 30); !reflect.DeepEqual(list, expected) {
+		t.Errorf("Incorrect expiring cards\n   expected:%v\n   got:     %v", expected, list)
+	}
+}
@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/uhppoted/uhppoted-lib/config"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/log"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var ExplainCmd = Explain{
+	workdir:     DEFAULT_WORKDIR,
+	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	rules:       filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
+	debug:       false,
+}
+
+type Explain struct {
+	workdir     string
+	credentials string
+	rules       string
+	member      string
+	debug       bool
+}
+
+func (cmd *Explain) Name() string {
+	return "explain"
+}
+
+func (cmd *Explain) Description() string {
+	return "Explains the access granted to a member by the ACL rules"
+}
+
+func (cmd *Explain) Usage() string {
+	return "--credentials <file> --rules <url> --member <card number|member ID|name>"
+}
+
+func (cmd *Explain) Help() {
+	fmt.Println()
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] explain [--credentials <file>] [--rules <url>] --member <card number|member ID|name>\n", APP)
+	fmt.Println()
+	fmt.Println("  Evaluates the ACL rules for a single member and lists the rules that fired (in order), the changes")
+	fmt.Println("  each rule made to the card permissions and the resulting access for each door")
+	fmt.Println()
+
+	helpOptions(cmd.FlagSet())
+
+	fmt.Println()
+	fmt.Println("  Examples:")
+	fmt.Println(`    uhppote-app-wild-apricot explain --credentials ".credentials/wild-apricot.json" \`)
+	fmt.Println(`                                     --rules "wild-apricot.grl" \`)
+	fmt.Println(`                                     --member 10058400`)
+	fmt.Println()
+}
+
+func (cmd *Explain) FlagSet() *flag.FlagSet {
+	flagset := flag.NewFlagSet("explain", flag.ExitOnError)
+
+	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
+	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file. Defaults to "+cmd.credentials)
+	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file (.grl) policy file (.yaml or .json) or CEL rules file (.cel). Support file path, HTTP, HTTPS and git+https/git+file and comma separated lists of URIs. Defaults to "+cmd.rules)
+	flagset.StringVar(&cmd.member, "member", cmd.member, "Card number, Wild Apricot member ID or name of the member")
+
+	return flagset
+}
+
+func (cmd *Explain) Execute(args ...any) error {
+	options := args[0].(*Options)
+
+	cmd.debug = options.Debug
+
+	log.SetDebug(options.Debug)
+
+	// ... check parameters
+	if strings.TrimSpace(cmd.credentials) == "" {
+		return fmt.Errorf("invalid credentials file")
+	}
+
+	if strings.TrimSpace(cmd.rules) == "" {
+		return fmt.Errorf("invalid rules file")
+	}
+
+	if strings.TrimSpace(cmd.member) == "" {
+		return fmt.Errorf("missing member card number, ID or name")
+	}
+
+	// ... get config, members, rules and doors
+	conf := config.NewConfig()
+	if err := conf.Load(options.Config); err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	settings, err := getSettings(options.Config, conf)
+	if err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	credentials, err := getCredentials(cmd.credentials)
+	if err != nil {
+		return err
+	}
+
+	members, err := getMembers(conf, credentials)
+	if err != nil {
+		return err
+	}
+
+	rules, err := getRules(cmd.rules, cmd.workdir, settings, cmd.debug)
+	if err != nil {
+		return err
+	}
+
+	doors, err := getDoors(conf)
+	if err != nil {
+		return err
+	}
+
+	return explain(os.Stdout, rules, members, doors, cmd.member)
+}
+
+// Evaluates the rules for the member identified by card number, member ID or name and writes
+// the explanation to w.
+func explain(w io.Writer, rules *acl.Rules, members *types.Members, doors []string, member string) error {
+	m, err := findMember(members, member)
+	if err != nil {
+		return err
+	}
+
+	x, err := rules.Explain(*m, doors)
+	if err != nil {
+		return err
+	}
+
+	printExplanation(w, x)
+
+	return nil
+}
+
+// Finds a member by card number or member ID (in that order) or by name.
+func findMember(members *types.Members, key string) (*types.Member, error) {
+	key = strings.TrimSpace(key)
+
+	if v, err := strconv.ParseUint(key, 10, 32); err == nil {
+		for _, m := range members.Members {
+			if m.CardNumber != nil && uint32(*m.CardNumber) == uint32(v) {
+				return &m, nil
+			}
+		}
+
+		for _, m := range members.Members {
+			if m.ID == uint32(v) {
+				return &m, nil
+			}
+		}
+	}
+
+	found := []types.Member{}
+	for _, m := range members.Members {
+		if normalise(m.Name) == normalise(key) {
+			found = append(found, m)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no member with card number, ID or name '%v'", key)
+
+	case 1:
+		return &found[0], nil
+
+	default:
+		return nil, fmt.Errorf("%v members with name '%v' - use the card number or member ID", len(found), key)
+	}
+}
+
+func printExplanation(f io.Writer, x *acl.Explanation) {
+	w := tabwriter.NewWriter(f, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintf(w, "  Member\t%v\n", x.Name)
+	if x.MemberID != 0 {
+		fmt.Fprintf(w, "  Member ID\t%v\n", x.MemberID)
+	}
+	fmt.Fprintf(w, "  Card Number\t%v\n", x.CardNumber)
+	fmt.Fprintf(w, "  From\t%v\n", x.StartDate)
+	fmt.Fprintf(w, "  To\t%v\n", x.EndDate)
+	w.Flush()
+
+	fmt.Fprintln(f)
+	fmt.Fprintln(f, "  Rules")
+	if len(x.Rules) == 0 {
+		fmt.Fprintln(f, "    (no rules fired)")
+	}
+
+	for i, step := range x.Rules {
+		changes := step.Changes
+		if len(changes) == 0 {
+			changes = []string{"(no changes)"}
+		}
+
+		for j, c := range changes {
+			if j == 0 {
+				fmt.Fprintf(w, "    %v\t%v\t%v\n", i+1, step.Rule, c)
+			} else {
+				fmt.Fprintf(w, "    \t\t%v\n", c)
+			}
+		}
+	}
+	w.Flush()
+
+	fmt.Fprintln(f)
+	fmt.Fprintln(f, "  Doors")
+	for _, d := range x.Doors {
+		fmt.Fprintf(w, "    %v\t%v\t%v\n", d.Door, d.Permission, d.Reason)
+	}
+	w.Flush()
+
+	if x.CardNumber == 0 {
+		fmt.Fprintln(f)
+		fmt.Fprintln(f, "  (member does not have a card number and is not included in the ACL)")
+	}
+
+	fmt.Fprintln(f)
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFindMember(t *testing.T) {
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	tests := map[string]string{
+		"6000001":          "Harry Potter",
+		"3":                "Hermione Granger",
+		"albus dumbledore": "Albus Dumbledore",
+	}
+
+	for key, expected := range tests {
+		if m, err := findMember(members, key); err != nil {
+			t.Errorf("Unexpected error finding member '%v' (%v)", key, err)
+		} else if m.Name != expected {
+			t.Errorf("Incorrect member for '%v' - expected:%v, got:%v", key, expected, m.Name)
+		}
+	}
+
+	if _, err := findMember(members, "Draco Malfoy"); err == nil {
+		t.Errorf("Expected error finding unknown member, got %v", err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	cmd := TestRules{
+		rules: "test_rules.grl",
+		doors: "test_doors.txt",
+	}
+
+	rules, err := cmd.getRules(&settings{})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	doors, err := cmd.getDoors("")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	var b bytes.Buffer
+	if err := explain(&b, rules, members, doors, "Tom Riddle"); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	for _, expected := range []string{
+		"1  Staff      granted Dungeon",
+		"2  Suspended  revoked Dungeon",
+		"Dungeon     N  revoked",
+	} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Explanation does not include '%v'\n%v", expected, b.String())
+		}
+	}
+}
@@ -2,18 +2,41 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 )
 
-func fetch(uri string) ([]byte, error) {
+// fetchOptions are the options for retrieving a file over HTTP(S) or from a git repository.
+// Revision (if not nil) is set to the commit hash of a file retrieved from a git repository.
+type fetchOptions struct {
+	Timeout  time.Duration
+	Headers  map[string]string
+	Cache    *httpCache
+	Workdir  string
+	Revision *string
+}
+
+// httpCache holds the ETag and Last-Modified validators for a stashed copy of a file, for
+// conditional HTTP requests.
+type httpCache struct {
+	File         string `json:"-"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last-modified,omitempty"`
+}
+
+func fetch(uri string, options fetchOptions) ([]byte, error) {
 	f := os.ReadFile
-	if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
-		f = fetchHTTP
+	if isGitURI(uri) {
+		f = func(url string) ([]byte, error) { return fetchGit(url, options) }
+	} else if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+		f = func(url string) ([]byte, error) { return fetchHTTP(url, options) }
 	} else if strings.HasPrefix(uri, "file://") {
 		f = fetchFile
 	}
@@ -21,22 +44,83 @@ func fetch(uri string) ([]byte, error) {
 	return f(uri)
 }
 
+// Returns true for an HTTP, HTTPS or remote git URI.
+func isRemote(uri string) bool {
+	for _, prefix := range []string{"http://", "https://", "git+http://", "git+https://"} {
+		if strings.HasPrefix(uri, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the (lowercase) host for an HTTP, HTTPS or git URI. Returns "" for a file path or
+// file URI.
+func host(uri string) string {
+	if u, err := url.Parse(strings.TrimPrefix(uri, "git+")); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return strings.ToLower(u.Host)
+	}
+
+	return ""
+}
+
 // Ref. https://stackoverflow.com/questions/18177419/download-public-file-from-google-drive-golang
 //
 //	Need to use https://drive.google.com/uc?export=download&id=<ID> for Google Drive shares.
-func fetchHTTP(url string) ([]byte, error) {
-	response, err := http.Get(url)
+func fetchHTTP(url string, options fetchOptions) ([]byte, error) {
+	client := http.Client{
+		Timeout: options.Timeout,
+	}
+
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range options.Headers {
+		request.Header.Set(k, v)
+	}
+
+	cache := options.Cache
+	if cache != nil {
+		if _, err := os.Stat(cache.File); err == nil {
+			if cache.ETag != "" {
+				request.Header.Set("If-None-Match", cache.ETag)
+			}
+
+			if cache.LastModified != "" {
+				request.Header.Set("If-Modified-Since", cache.LastModified)
+			}
+		}
+	}
+
+	response, err := client.Do(request)
 	if err != nil {
 		return nil, err
 	}
 
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusNotModified && cache != nil {
+		debugf("%v not modified - using stashed copy %v", url, cache.File)
+		return os.ReadFile(cache.File)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, fmt.Errorf("error retrieving %v (%v)", url, response.Status)
+	}
+
 	var b bytes.Buffer
 	if _, err = io.Copy(&b, response.Body); err != nil {
 		return nil, err
 	}
 
+	if cache != nil {
+		cache.ETag = response.Header.Get("ETag")
+		cache.LastModified = response.Header.Get("Last-Modified")
+	}
+
 	return b.Bytes(), nil
 }
 
@@ -48,3 +132,50 @@ func fetchFile(url string) ([]byte, error) {
 
 	return os.ReadFile(match[1])
 }
+
+// Loads the ETag and Last-Modified validators for a stashed file from the '.http' file
+// alongside the stashed file.
+func loadHTTPCache(file string) *httpCache {
+	cache := httpCache{
+		File: file,
+	}
+
+	if b, err := os.ReadFile(file + ".http"); err == nil {
+		if err := json.Unmarshal(b, &cache); err != nil {
+			warnf("Invalid HTTP cache file %v (%v)", file+".http", err)
+		}
+	}
+
+	return &cache
+}
+
+// Saves the ETag and Last-Modified validators for the stashed file.
+func (c *httpCache) save() {
+	if c != nil {
+		if c.ETag == "" && c.LastModified == "" {
+			os.Remove(c.File + ".http")
+		} else if b, err := json.MarshalIndent(c, "", "  "); err != nil {
+			warnf("Error saving HTTP cache file (%v)", err)
+		} else if err := os.WriteFile(c.File+".http", b, 0644); err != nil {
+			warnf("Error saving HTTP cache file (%v)", err)
+		}
+	}
+}
+
+// Reads the optional custom HTTP headers file, a JSON object with the header names and values.
+func getHeaders(file string) (map[string]string, error) {
+	headers := map[string]string{}
+
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read HTTP headers file (%v)", err)
+		}
+
+		if err := json.Unmarshal(b, &headers); err != nil {
+			return nil, fmt.Errorf("invalid HTTP headers file %v (%v)", file, err)
+		}
+	}
+
+	return headers, nil
+}
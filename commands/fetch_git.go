@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// gitURI is a parsed git+https:// or git+file:// rules URI i.e.
+//
+//	git+https://github.com/org/repo.git//path/to/wild-apricot.grl?ref=<branch|tag|commit>
+type gitURI struct {
+	Repository string
+	Path       string
+	Ref        string
+}
+
+func isGitURI(uri string) bool {
+	return strings.HasPrefix(uri, "git+https://") || strings.HasPrefix(uri, "git+http://") || strings.HasPrefix(uri, "git+file://")
+}
+
+func parseGitURI(uri string) (gitURI, error) {
+	u := strings.TrimPrefix(uri, "git+")
+	ref := ""
+
+	if ix := strings.Index(u, "?"); ix >= 0 {
+		query, err := url.ParseQuery(u[ix+1:])
+		if err != nil {
+			return gitURI{}, fmt.Errorf("invalid git URI %v (%v)", uri, err)
+		}
+
+		ref = query.Get("ref")
+		u = u[:ix]
+	}
+
+	scheme := strings.Index(u, "://") + 3
+	ix := strings.Index(u[scheme:], "//")
+	if ix < 0 {
+		return gitURI{}, fmt.Errorf("invalid git URI %v (missing '//<path>' to the rules file in the repository)", uri)
+	}
+
+	repository := u[:scheme+ix]
+	path := strings.Trim(u[scheme+ix+2:], "/")
+	if path == "" {
+		return gitURI{}, fmt.Errorf("invalid git URI %v (missing path to the rules file in the repository)", uri)
+	}
+
+	return gitURI{
+		Repository: repository,
+		Path:       path,
+		Ref:        ref,
+	}, nil
+}
+
+// fetchGit fetches the repository into a bare clone in the 'git' subdirectory of the workdir,
+// resolves the configured branch, tag or commit (defaulting to the remote HEAD) and returns
+// the contents of the rules file at that commit. The commit hash is returned in the Revision
+// field of the fetch options. The remote requests are limited to the fetch options timeout.
+func fetchGit(uri string, options fetchOptions) ([]byte, error) {
+	g, err := parseGitURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	hash := sha256.Sum256([]byte(g.Repository))
+	dir := filepath.Join(options.Workdir, "git", hex.EncodeToString(hash[:8]))
+	auth := gitAuth(options.Headers)
+
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if err := os.MkdirAll(dir, 0770); err != nil {
+			return nil, err
+		} else if repo, err = git.PlainInit(dir, true); err != nil {
+			return nil, err
+		} else if _, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{g.Repository}}); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	debugf("fetching %v into %v", g.Repository, dir)
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs: []config.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+		Auth:  auth,
+		Force: true,
+	})
+
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, fmt.Errorf("error fetching %v (%v)", g.Repository, err)
+	}
+
+	commit, err := resolveGitRef(ctx, repo, g.Ref, auth)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", g.Repository, err)
+	}
+
+	file, err := commit.File(g.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%v@%v: %v (%v)", g.Repository, commit.Hash.String()[:7], g.Path, err)
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Revision != nil {
+		*options.Revision = commit.Hash.String()
+	}
+
+	return []byte(contents), nil
+}
+
+// Resolves a branch, tag or (possibly abbreviated) commit hash to a commit. Defaults to the
+// branch referenced by the remote HEAD if the ref is blank.
+func resolveGitRef(ctx context.Context, repo *git.Repository, ref string, auth transport.AuthMethod) (*object.Commit, error) {
+	if ref == "" {
+		remote, err := repo.Remote("origin")
+		if err != nil {
+			return nil, err
+		}
+
+		refs, err := remote.ListContext(ctx, &git.ListOptions{Auth: auth})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range refs {
+			if r.Name() == plumbing.HEAD {
+				if r.Type() == plumbing.SymbolicReference {
+					ref = r.Target().Short()
+				} else {
+					ref = r.Hash().String()
+				}
+			}
+		}
+
+		if ref == "" {
+			return nil, fmt.Errorf("unable to resolve remote HEAD")
+		}
+	}
+
+	revisions := []string{
+		"refs/remotes/origin/" + ref,
+		"refs/tags/" + ref,
+		ref,
+	}
+
+	for _, revision := range revisions {
+		if hash, err := repo.ResolveRevision(plumbing.Revision(revision)); err == nil {
+			// ... annotated tag?
+			if tag, err := repo.TagObject(*hash); err == nil {
+				return tag.Commit()
+			}
+
+			return repo.CommitObject(*hash)
+		}
+	}
+
+	return nil, fmt.Errorf("unknown branch, tag or commit '%v'", ref)
+}
+
+// Uses the Authorization header (if any) from the custom HTTP headers for git+https
+// repositories i.e. 'Bearer <token>' or 'Basic <base64 user:password>'.
+func gitAuth(headers map[string]string) transport.AuthMethod {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Authorization") {
+			scheme, credentials, _ := strings.Cut(strings.TrimSpace(v), " ")
+
+			switch strings.ToLower(scheme) {
+			case "bearer":
+				return &githttp.TokenAuth{Token: credentials}
+
+			case "basic":
+				if b, err := base64.StdEncoding.DecodeString(credentials); err == nil {
+					user, password, _ := strings.Cut(string(b), ":")
+					return &githttp.BasicAuth{Username: user, Password: password}
+				}
+			}
+		}
+	}
+
+	return nil
+}
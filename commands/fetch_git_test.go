@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestParseGitURI(t *testing.T) {
+	tests := []struct {
+		uri      string
+		expected gitURI
+	}{
+		{"git+https://github.com/hogwarts/rules.git//wild-apricot.grl", gitURI{"https://github.com/hogwarts/rules.git", "wild-apricot.grl", ""}},
+		{"git+https://github.com/hogwarts/rules.git//acl/wild-apricot.cel?ref=v1.0", gitURI{"https://github.com/hogwarts/rules.git", "acl/wild-apricot.cel", "v1.0"}},
+		{"git+file:///srv/git/rules//wild-apricot.yaml?ref=main", gitURI{"file:///srv/git/rules", "wild-apricot.yaml", "main"}},
+	}
+
+	for _, test := range tests {
+		if g, err := parseGitURI(test.uri); err != nil {
+			t.Errorf("%v: unexpected error (%v)", test.uri, err)
+		} else if g != test.expected {
+			t.Errorf("%v: incorrect git URI\n   expected:%+v\n   got:     %+v", test.uri, test.expected, g)
+		}
+	}
+
+	if _, err := parseGitURI("git+https://github.com/hogwarts/rules.git"); err == nil {
+		t.Errorf("Expected error for git URI without path, got %v", err)
+	}
+}
+
+func TestFetchGit(t *testing.T) {
+	v1 := "// *** GRULES ***\n// v1\n// *** END GRULES ***\n"
+	v2 := "// *** GRULES ***\n// v2\n// *** END GRULES ***\n"
+
+	// ... create upstream repository
+	upstream := t.TempDir()
+	repo, err := git.PlainInit(upstream, false)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	commit := func(rules string) string {
+		if err := os.MkdirAll(filepath.Join(upstream, "acl"), 0755); err != nil {
+			t.Fatalf("%v", err)
+		} else if err := os.WriteFile(filepath.Join(upstream, "acl", "wild-apricot.grl"), []byte(rules), 0644); err != nil {
+			t.Fatalf("%v", err)
+		} else if _, err := worktree.Add("acl/wild-apricot.grl"); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		signature := object.Signature{Name: "Albus Dumbledore", Email: "albus@hogwarts.edu", When: time.Now()}
+		hash, err := worktree.Commit("rules", &git.CommitOptions{Author: &signature})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		return hash.String()
+	}
+
+	first := commit(v1)
+	if _, err := repo.CreateTag("v1", plumbing.NewHash(first), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "Albus Dumbledore", Email: "albus@hogwarts.edu", When: time.Now()},
+		Message: "v1",
+	}); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	second := commit(v2)
+
+	// ... fetch
+	workdir := t.TempDir()
+	tests := []struct {
+		ref      string
+		rules    string
+		revision string
+	}{
+		{"", v2, second},
+		{"master", v2, second},
+		{"v1", v1, first},
+		{first, v1, first},
+		{first[:7], v1, first},
+	}
+
+	for _, test := range tests {
+		uri := "git+file://" + upstream + "//acl/wild-apricot.grl"
+		if test.ref != "" {
+			uri += "?ref=" + test.ref
+		}
+
+		revision := ""
+		options := fetchOptions{
+			Workdir:  workdir,
+			Revision: &revision,
+		}
+
+		if b, err := fetch(uri, options); err != nil {
+			t.Errorf("%v: unexpected error (%v)", uri, err)
+		} else if string(b) != test.rules {
+			t.Errorf("%v: incorrect rules\n   expected:%v\n   got:     %v", uri, test.rules, string(b))
+		} else if revision != test.revision {
+			t.Errorf("%v: incorrect revision - expected:%v, got:%v", uri, test.revision, revision)
+		}
+	}
+
+	if _, err := fetch("git+file://"+upstream+"//acl/wild-apricot.grl?ref=v2", fetchOptions{Workdir: workdir}); err == nil {
+		t.Errorf("Expected error for unknown ref, got %v", err)
+	}
+
+	if _, err := fetch("git+file://"+upstream+"//acl/missing.grl", fetchOptions{Workdir: workdir}); err == nil {
+		t.Errorf("Expected error for missing file, got %v", err)
+	}
+}
+
+func TestFetchGitWithTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+
+	defer server.Close()
+	defer close(done)
+
+	uri := "git+" + server.URL + "/hogwarts.git//acl/wild-apricot.grl"
+	options := fetchOptions{
+		Workdir: t.TempDir(),
+		Timeout: 250 * time.Millisecond,
+	}
+
+	start := time.Now()
+	if _, err := fetch(uri, options); err == nil {
+		t.Errorf("Expected timeout error, got %v", err)
+	} else if dt := time.Since(start); dt > 5*time.Second {
+		t.Errorf("Expected fetch to time out after %v, took %v", options.Timeout, dt)
+	}
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFetchHTTP(t *testing.T) {
+	rules := "// *** GRULES ***\n// *** END GRULES ***\n"
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		switch {
+		case r.Header.Get("Authorization") != "Bearer qwerty":
+			w.WriteHeader(http.StatusUnauthorized)
+
+		case r.URL.Path == "/missing.grl":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<html>Not Found</html>"))
+
+		case r.Header.Get("If-None-Match") == `"v1"`:
+			w.WriteHeader(http.StatusNotModified)
+
+		default:
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(rules))
+		}
+	}))
+
+	defer server.Close()
+
+	stashed := filepath.Join(t.TempDir(), "wild-apricot.grl")
+	cache := loadHTTPCache(stashed)
+	options := fetchOptions{
+		Timeout: 5 * time.Second,
+		Headers: map[string]string{"Authorization": "Bearer qwerty"},
+		Cache:   cache,
+	}
+
+	// ... unauthorized
+	if _, err := fetch(server.URL+"/wild-apricot.grl", fetchOptions{Timeout: 5 * time.Second}); err == nil {
+		t.Errorf("Expected error for unauthorized request, got %v", err)
+	}
+
+	// ... not found
+	if _, err := fetch(server.URL+"/missing.grl", options); err == nil {
+		t.Errorf("Expected error for 404 response, got %v", err)
+	}
+
+	// ... download
+	if b, err := fetch(server.URL+"/wild-apricot.grl", options); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	} else if string(b) != rules {
+		t.Errorf("Incorrect rules\n   expected:%v\n   got:     %v", rules, string(b))
+	} else if cache.ETag != `"v1"` {
+		t.Errorf("Incorrect ETag - expected:%v, got:%v", `"v1"`, cache.ETag)
+	}
+
+	// ... not modified (stashed copy is distinguishable from the download)
+	if err := os.WriteFile(stashed, []byte(rules+"// stashed\n"), 0644); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	cache.save()
+
+	options.Cache = loadHTTPCache(stashed)
+	if b, err := fetch(server.URL+"/wild-apricot.grl", options); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	} else if string(b) != rules+"// stashed\n" {
+		t.Errorf("Incorrect rules\n   expected:%v\n   got:     %v", rules+"// stashed\n", string(b))
+	}
+
+	if requests != 4 {
+		t.Errorf("Incorrect number of requests - expected:%v, got:%v", 4, requests)
+	}
+}
+
+func TestFetchOptionsHeaders(t *testing.T) {
+	s := settings{
+		headers: map[string]string{"Authorization": "Bearer qwerty"},
+	}
+
+	origins := []string{"rules.example.com"}
+
+	tests := []struct {
+		uri      string
+		expected bool
+	}{
+		{"https://rules.example.com/acl/wild-apricot.grl", true},
+		{"https://rules.example.com/acl/wild-apricot.grl.minisig", true},
+		{"git+https://RULES.example.com/hogwarts/rules.git//acl/wild-apricot.grl", true},
+		{"https://signatures.example.org/wild-apricot.grl.minisig", false},
+		{"https://example.com/common.grl", false},
+		{"/etc/uhppoted/common.grl", false},
+	}
+
+	for _, test := range tests {
+		options := s.fetchOptions(test.uri, origins, "", nil)
+		if headers := options.Headers != nil; headers != test.expected {
+			t.Errorf("%v: incorrect custom headers\n   expected:%v\n   got:     %v", test.uri, test.expected, headers)
+		}
+	}
+}
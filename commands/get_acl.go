@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/uhppoted/uhppote-core/uhppote"
 	lib "github.com/uhppoted/uhppoted-lib/acl"
 	"github.com/uhppoted/uhppoted-lib/config"
 	"github.com/uhppoted/uhppoted-lib/lockfile"
@@ -18,22 +18,28 @@ import (
 )
 
 var GetACLCmd = GetACL{
-	workdir:     DEFAULT_WORKDIR,
-	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
-	rules:       filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
-	withPIN:     false,
-	lockfile:    "",
-	debug:       false,
+	workdir:      DEFAULT_WORKDIR,
+	credentials:  filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	rules:        filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
+	withPIN:      false,
+	withName:     false,
+	withMemberID: false,
+	lockfile:     "",
+	debug:        false,
 }
 
 type GetACL struct {
-	workdir     string
-	credentials string
-	rules       string
-	file        string
-	withPIN     bool
-	lockfile    string
-	debug       bool
+	workdir      string
+	credentials  string
+	rules        string
+	file         string
+	withPIN      bool
+	withName     bool
+	withMemberID bool
+	explain      string
+	asOf         string
+	lockfile     string
+	debug        bool
 }
 
 func (cmd *GetACL) Name() string {
@@ -50,7 +56,7 @@ func (cmd *GetACL) Usage() string {
 
 func (cmd *GetACL) Help() {
 	fmt.Println()
-	fmt.Printf("  Usage: %s [--debug] [--config <file>] get-acl [--credentials <file>] [--with-pin] [--rules <url>] [--file <file>]\n", APP)
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] get-acl [--credentials <file>] [--with-pin] [--with-name] [--with-member-id] [--explain <member>] [--as-of <date>] [--rules <url>] [--file <file>]\n", APP)
 	fmt.Println()
 	fmt.Println("  Downloads an access control list from a Wild Apricot member database, applies the ACL rules and")
 	fmt.Println("  stores the generated access control list to a TSV file")
@@ -72,9 +78,13 @@ func (cmd *GetACL) FlagSet() *flag.FlagSet {
 
 	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
 	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file. Defaults to "+cmd.credentials)
-	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file. Support file path, HTTP and HTTPS. Defaults to "+cmd.rules)
+	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file (.grl) policy file (.yaml or .json) or CEL rules file (.cel). Support file path, HTTP, HTTPS and git+https/git+file and comma separated lists of URIs. Defaults to "+cmd.rules)
 	flagset.StringVar(&cmd.file, "file", cmd.file, "Output file name. Defaults to stdout")
 	flagset.BoolVar(&cmd.withPIN, "with-pin", cmd.withPIN, "Include card keypad PIN code in retrieved ACL information")
+	flagset.BoolVar(&cmd.withName, "with-name", cmd.withName, "Include card holder name in retrieved ACL information")
+	flagset.BoolVar(&cmd.withMemberID, "with-member-id", cmd.withMemberID, "Include Wild Apricot member ID in retrieved ACL information")
+	flagset.StringVar(&cmd.explain, "explain", cmd.explain, "Explains the access granted to a member (card number, member ID or name) by the ACL rules")
+	flagset.StringVar(&cmd.asOf, "as-of", cmd.asOf, "Generates the ACL as of a date (YYYY-MM-DD) e.g. to preview the ACL after a membership renewal date. Defaults to today")
 	flagset.StringVar(&cmd.lockfile, "lockfile", cmd.lockfile, fmt.Sprintf("Filepath for lock file. Defaults to %v", lockfile))
 
 	return flagset
@@ -94,6 +104,11 @@ func (cmd *GetACL) Execute(args ...any) error {
 		return fmt.Errorf("invalid rules file")
 	}
 
+	clock, err := getClock(cmd.asOf)
+	if err != nil {
+		return err
+	}
+
 	// ... locked?
 	lockFile := config.Lockfile{
 		File:   filepath.Join(cmd.workdir, ".wild-apricot", "uhppoted-app-wild-apricot.lock"),
@@ -122,6 +137,11 @@ func (cmd *GetACL) Execute(args ...any) error {
 		return fmt.Errorf("could not load configuration (%v)", err)
 	}
 
+	settings, err := getSettings(options.Config, conf)
+	if err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
 	credentials, err := getCredentials(cmd.credentials)
 	if err != nil {
 		return err
@@ -132,9 +152,8 @@ func (cmd *GetACL) Execute(args ...any) error {
 		return err
 	}
 
-	rules, err := getRules(cmd.rules, cmd.workdir, cmd.debug)
-	if err != nil {
-		return err
+	if clock != nil {
+		infof("Generating ACL as of %v", clock.Today())
 	}
 
 	if cmd.debug {
@@ -145,12 +164,36 @@ func (cmd *GetACL) Execute(args ...any) error {
 		}
 	}
 
-	// ... create ACL
-	doors, err := getDoors(conf)
+	// ... create ACL for each site
+	u, devices := getDevices(conf, cmd.debug)
+
+	sites, err := getSites(settings.Sites, cmd.rules, devices)
+	if err != nil {
+		return err
+	}
+
+	for _, site := range sites {
+		if err := cmd.getSiteACL(conf, settings, site, members, clock, u, len(sites) > 1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Generates the ACL for a site from the site rules file and the doors on the site controllers and
+// writes it to the console or the output file. The ACL for a named site is written to the output
+// file with the site name inserted before the extension.
+func (cmd *GetACL) getSiteACL(conf *config.Config, settings *settings, site site, members *types.Members, clock types.Clock, u uhppote.IUHPPOTE, multisite bool) error {
+	rules, doors, err := getSiteRules(conf, settings, site, cmd.workdir, cmd.debug)
 	if err != nil {
 		return err
 	}
 
+	if clock != nil {
+		rules.SetClock(clock)
+	}
+
 	if cmd.debug {
 		fmt.Printf("DOORS:\n")
 		for _, d := range doors {
@@ -167,11 +210,15 @@ func (cmd *GetACL) Execute(args ...any) error {
 		}
 	}
 
+	getPreviousACL(rules, settings, u, site.Devices)
+
 	ACL, err := makeACL(*members, doors)
 	if err != nil {
 		return err
 	}
 
+	logFailures(ACL, settings.evaluation.OnError)
+
 	asTable := func(a *acl.ACL) *lib.Table {
 		if cmd.withPIN {
 			return a.AsTableWithPIN()
@@ -180,8 +227,7 @@ func (cmd *GetACL) Execute(args ...any) error {
 		}
 	}
 
-	_, devices := getDevices(conf, cmd.debug)
-	_, warnings, err := lib.ParseTable(asTable(ACL), devices, false)
+	_, warnings, err := lib.ParseTable(asTable(ACL), site.Devices, false)
 	if err != nil {
 		return err
 	}
@@ -199,30 +245,38 @@ func (cmd *GetACL) Execute(args ...any) error {
 	}
 
 	// ... write to stdout
-	if cmd.file == "" {
-		fmt.Fprintln(os.Stdout, string(asTable(ACL).MarshalTextIndent("  ", " ")))
-		return nil
+	columns := acl.Columns{
+		PIN:      cmd.withPIN,
+		Name:     cmd.withName,
+		MemberID: cmd.withMemberID,
 	}
 
-	// ... write to TSV file
-	asTSV := func(a *acl.ACL, w io.Writer) error {
-		if cmd.withPIN {
-			return a.ToTSVWithPIN(w)
-		} else {
-			return a.ToTSV(w)
+	if cmd.file == "" {
+		if multisite {
+			fmt.Fprintf(os.Stdout, "  Site %v\n\n", site)
 		}
-	}
 
-	var b bytes.Buffer
-	if err := asTSV(ACL, &b); err != nil {
-		return fmt.Errorf("error creating TSV file (%v)", err)
-	}
+		fmt.Fprintln(os.Stdout, string(ACL.AsTableWith(columns).MarshalTextIndent("  ", " ")))
+	} else {
+		// ... write to TSV file
+		file := siteFile(cmd.file, site)
 
-	if err := write(cmd.file, b.Bytes()); err != nil {
-		return err
+		var b bytes.Buffer
+		if err := ACL.ToTSVWith(&b, columns); err != nil {
+			return fmt.Errorf("error creating TSV file (%v)", err)
+		}
+
+		if err := write(file, b.Bytes()); err != nil {
+			return err
+		}
+
+		infof("ACL saved to %s", file)
 	}
 
-	infof("ACL saved to %s", cmd.file)
+	// ... explain?
+	if cmd.explain != "" {
+		return explain(os.Stdout, rules, members, doors, cmd.explain)
+	}
 
 	return nil
 }
@@ -67,6 +67,11 @@ func (cmd *GetDoors) Execute(args ...any) error {
 		return fmt.Errorf("could not load configuration (%v)", err)
 	}
 
+	settings, err := getSettings(options.Config, conf)
+	if err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
 	doors, err := getDoors(conf)
 	if err != nil {
 		return err
@@ -74,13 +79,13 @@ func (cmd *GetDoors) Execute(args ...any) error {
 
 	// ... write to stdout
 	if cmd.file == "" {
-		fmt.Fprintln(os.Stdout, string(doors.AsTable().MarshalTextIndent("  ", " ")))
+		fmt.Fprintln(os.Stdout, string(doors.AsTable(settings.zones).MarshalTextIndent("  ", " ")))
 		return nil
 	}
 
 	// ... write to TSV file
 	var b bytes.Buffer
-	if err := doors.AsTable().ToTSV(&b); err != nil {
+	if err := doors.AsTable(settings.zones).ToTSV(&b); err != nil {
 		return fmt.Errorf("error creating TSV file (%v)", err)
 	}
 
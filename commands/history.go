@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	core "github.com/uhppoted/uhppote-core/types"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/log"
+)
+
+var HistoryCmd = History{
+	workdir:     DEFAULT_WORKDIR,
+	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	format:      "",
+	debug:       false,
+}
+
+type History struct {
+	workdir     string
+	credentials string
+	card        string
+	member      string
+	door        string
+	from        string
+	to          string
+	format      string
+	file        string
+	debug       bool
+}
+
+func (cmd *History) Name() string {
+	return "history"
+}
+
+func (cmd *History) Description() string {
+	return "Lists the card changes recorded in the load-acl audit store"
+}
+
+func (cmd *History) Usage() string {
+	return "--credentials <file> [--card <card number>] [--member <member ID|name>] [--door <door>] [--from <date>] [--to <date>]"
+}
+
+func (cmd *History) Help() {
+	fmt.Println()
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] history [--credentials <file>] [--card <card number>] [--member <member ID|name>] [--door <door>] [--from <date>] [--to <date>] [--format table|tsv|json] [--file <file>]\n", APP)
+	fmt.Println()
+	fmt.Println("  Lists the cards added, updated and deleted on each controller by load-acl, as recorded in the audit store")
+	fmt.Println("  in the workdir, optionally filtered by card number, member, door and date range")
+	fmt.Println()
+
+	helpOptions(cmd.FlagSet())
+
+	fmt.Println()
+	fmt.Println("  Examples:")
+	fmt.Println(`    uhppote-app-wild-apricot history --card 10058400`)
+	fmt.Println(`    uhppote-app-wild-apricot history --door "Great Hall" --from 2026-01-01 --to 2026-01-31`)
+	fmt.Println()
+}
+
+func (cmd *History) FlagSet() *flag.FlagSet {
+	flagset := flag.NewFlagSet("history", flag.ExitOnError)
+
+	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
+	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file (for the account ID). Defaults to "+cmd.credentials)
+	flagset.StringVar(&cmd.card, "card", cmd.card, "Lists only the changes for a card number")
+	flagset.StringVar(&cmd.member, "member", cmd.member, "Lists only the changes for a member (Wild Apricot member ID or name)")
+	flagset.StringVar(&cmd.door, "door", cmd.door, "Lists only the changes for cards with access to a door before or after the change")
+	flagset.StringVar(&cmd.from, "from", cmd.from, "Lists only the changes on or after a date (YYYY-MM-DD)")
+	flagset.StringVar(&cmd.to, "to", cmd.to, "Lists only the changes on or before a date (YYYY-MM-DD)")
+	flagset.StringVar(&cmd.format, "format", cmd.format, "Output format (table, tsv or json). Defaults to the --file extension or 'table'")
+	flagset.StringVar(&cmd.file, "file", cmd.file, "Output file name. Defaults to stdout")
+
+	return flagset
+}
+
+func (cmd *History) Execute(args ...any) error {
+	options := args[0].(*Options)
+
+	cmd.debug = options.Debug
+
+	log.SetDebug(options.Debug)
+
+	// ... check parameters
+	if strings.TrimSpace(cmd.credentials) == "" {
+		return fmt.Errorf("invalid credentials file")
+	}
+
+	query, err := cmd.query()
+	if err != nil {
+		return err
+	}
+
+	format, err := outputFormat(cmd.format, cmd.file)
+	if err != nil {
+		return err
+	}
+
+	// ... query audit store
+	credentials, err := getCredentials(cmd.credentials)
+	if err != nil {
+		return err
+	}
+
+	records, err := readAudit(cmd.workdir, credentials.AccountID)
+	if err != nil {
+		return err
+	}
+
+	entries := query.find(records)
+
+	if err := output(entries, historyTable(entries), format, cmd.file); err != nil {
+		return err
+	}
+
+	if cmd.file != "" {
+		infof("%v audit history entries saved to %s", len(entries), cmd.file)
+	}
+
+	return nil
+}
+
+func (cmd *History) query() (auditQuery, error) {
+	query := auditQuery{
+		Member: strings.TrimSpace(cmd.member),
+		Door:   strings.TrimSpace(cmd.door),
+	}
+
+	if card := strings.TrimSpace(cmd.card); card != "" {
+		if v, err := strconv.ParseUint(card, 10, 32); err != nil || v == 0 {
+			return query, fmt.Errorf("invalid card number '%v'", cmd.card)
+		} else {
+			query.CardNumber = uint32(v)
+		}
+	}
+
+	date := func(s string, option string) (core.Date, error) {
+		if strings.TrimSpace(s) == "" {
+			return core.Date{}, nil
+		}
+
+		if d, err := core.ParseDate(strings.TrimSpace(s)); err != nil || d.IsZero() {
+			return core.Date{}, fmt.Errorf("invalid --%v date '%v'", option, s)
+		} else {
+			return d, nil
+		}
+	}
+
+	var err error
+	if query.From, err = date(cmd.from, "from"); err != nil {
+		return query, err
+	}
+
+	if query.To, err = date(cmd.to, "to"); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+func historyTable(entries []auditEntry) *lib.Table {
+	table := lib.Table{
+		Header:  []string{"Timestamp", "Controller", "Action", "Card Number", "Member ID", "Name", "Doors", "Previous Doors", "Rules"},
+		Records: [][]string{},
+	}
+
+	// ... abbreviate commit hashes and rules hashes
+	abbreviate := func(s string) string {
+		if len(s) > 12 {
+			return s[:12]
+		}
+
+		return s
+	}
+
+	for _, e := range entries {
+		table.Records = append(table.Records, []string{
+			e.Timestamp.Format("2006-01-02 15:04:05"),
+			fmt.Sprintf("%v", e.Controller),
+			e.Action,
+			fmt.Sprintf("%v", e.CardNumber),
+			memberID(e.MemberID),
+			e.Name,
+			strings.Join(e.Doors, ", "),
+			strings.Join(e.Previous, ", "),
+			abbreviate(e.Rules),
+		})
+	}
+
+	return &table
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Returns the rules files referenced by the '@include' directives in a rules file i.e. a
+// comment line of the form:
+//
+//	// @include common.grl
+//
+// (or '# @include common.yaml' in a YAML policy file).
+func includes(ruleset []byte) []string {
+	re := regexp.MustCompile(`^\s*(?://|#)\s*@include\s+"?([^"\s]+)"?\s*$`)
+	list := []string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(ruleset))
+	for scanner.Scan() {
+		if match := re.FindStringSubmatch(scanner.Text()); match != nil {
+			list = append(list, match[1])
+		}
+	}
+
+	return list
+}
+
+// Resolves an included rules file URI relative to the including rules file URI. Absolute URIs
+// and paths are returned unchanged.
+func resolveInclude(uri string, include string) string {
+	if strings.Contains(include, "://") || filepath.IsAbs(include) {
+		return include
+	}
+
+	switch {
+	case isGitURI(uri):
+		if g, err := parseGitURI(uri); err == nil {
+			resolved := "git+" + g.Repository + "//" + path.Join(path.Dir(g.Path), include)
+			if g.Ref != "" {
+				resolved += "?ref=" + url.QueryEscape(g.Ref)
+			}
+
+			return resolved
+		}
+
+	case strings.Contains(uri, "://"):
+		if base, err := url.Parse(uri); err == nil {
+			if ref, err := url.Parse(include); err == nil {
+				return base.ResolveReference(ref).String()
+			}
+		}
+	}
+
+	return filepath.Join(filepath.Dir(uri), include)
+}
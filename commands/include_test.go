@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveInclude(t *testing.T) {
+	tests := []struct {
+		uri      string
+		include  string
+		expected string
+	}{
+		{"/etc/uhppoted/site.grl", "common.grl", "/etc/uhppoted/common.grl"},
+		{"/etc/uhppoted/site.grl", "/var/uhppoted/common.grl", "/var/uhppoted/common.grl"},
+		{"https://example.com/rules/site.grl", "common.grl", "https://example.com/rules/common.grl"},
+		{"https://example.com/rules/site.grl", "../common.grl", "https://example.com/common.grl"},
+		{"https://example.com/rules/site.grl", "file:///etc/common.grl", "file:///etc/common.grl"},
+		{"git+https://github.com/hogwarts/rules.git//acl/site.grl?ref=v1", "common.grl", "git+https://github.com/hogwarts/rules.git//acl/common.grl?ref=v1"},
+	}
+
+	for _, test := range tests {
+		if uri := resolveInclude(test.uri, test.include); uri != test.expected {
+			t.Errorf("%v: incorrect include URI\n   expected:%v\n   got:     %v", test.include, test.expected, uri)
+		}
+	}
+}
+
+func TestLoadRulesWithIncludes(t *testing.T) {
+	workdir := t.TempDir()
+	common := "// *** GRULES ***\nrule Everyone \"\" { when true then permissions.Grant(\"*\"); Retract(\"Everyone\"); }\n// *** END GRULES ***\n"
+	site := "// *** GRULES ***\n// @include common.grl\nrule Site \"\" salience -10 { when true then permissions.Revoke(\"Hogsmeade\"); Retract(\"Site\"); }\n// *** END GRULES ***\n"
+	extra := "rules:\n  - name: Extra\n    grant: [ Great Hall ]\n"
+
+	files := map[string]string{
+		"common.grl": common,
+		"site.grl":   site,
+		"extra.yaml": extra,
+	}
+
+	for k, v := range files {
+		if err := os.WriteFile(filepath.Join(workdir, k), []byte(v), 0600); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	uri := filepath.Join(workdir, "site.grl") + "," + filepath.Join(workdir, "extra.yaml")
+	if _, err := loadRules(uri, workdir, &settings{}, false); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	stashed := map[string]string{
+		"wild-apricot.grl": site,
+		stashName(filepath.Join(workdir, "common.grl"), ".grl"):  common,
+		stashName(filepath.Join(workdir, "extra.yaml"), ".yaml"): extra,
+	}
+
+	for k, v := range stashed {
+		if b, err := os.ReadFile(filepath.Join(workdir, k)); err != nil {
+			t.Errorf("%v: %v", k, err)
+		} else if string(b) != v {
+			t.Errorf("%v: incorrect stashed rules\n   expected:%v\n   got:     %v", k, v, string(b))
+		}
+	}
+
+	// ... included more than once
+	uri = filepath.Join(workdir, "site.grl") + "," + filepath.Join(workdir, "common.grl")
+	if _, err := loadRules(uri, workdir, &settings{}, false); err != nil {
+		t.Errorf("Unexpected error for rules file included more than once (%v)", err)
+	}
+}
+
+func TestLoadRulesWithDiamondIncludes(t *testing.T) {
+	workdir := t.TempDir()
+
+	files := map[string]string{
+		"common.grl":  "// *** GRULES ***\nrule Everyone \"\" { when true then permissions.Grant(\"*\"); Retract(\"Everyone\"); }\n// *** END GRULES ***\n",
+		"north.grl":   "// *** GRULES ***\n// @include common.grl\nrule North \"\" salience -10 { when true then permissions.Revoke(\"Hogsmeade\"); Retract(\"North\"); }\n// *** END GRULES ***\n",
+		"south.grl":   "// *** GRULES ***\n// @include common.grl\nrule South \"\" salience -10 { when true then permissions.Revoke(\"Dungeon\"); Retract(\"South\"); }\n// *** END GRULES ***\n",
+		"site.grl":    "// *** GRULES ***\n// @include north.grl\n// @include south.grl\n// *** END GRULES ***\n",
+		"cycle-a.grl": "// *** GRULES ***\n// @include cycle-b.grl\n// *** END GRULES ***\n",
+		"cycle-b.grl": "// *** GRULES ***\n// @include cycle-a.grl\n// *** END GRULES ***\n",
+	}
+
+	for k, v := range files {
+		if err := os.WriteFile(filepath.Join(workdir, k), []byte(v), 0600); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	if _, err := loadRules(filepath.Join(workdir, "site.grl"), workdir, &settings{}, false); err != nil {
+		t.Errorf("Unexpected error for diamond includes (%v)", err)
+	}
+
+	if _, err := loadRules(filepath.Join(workdir, "cycle-a.grl"), workdir, &settings{}, false); err == nil {
+		t.Errorf("Expected error for cyclic includes, got %v", err)
+	}
+}
+
+func TestLoadRulesWithUnverifiedRemoteInclude(t *testing.T) {
+	workdir := t.TempDir()
+	site := "// *** GRULES ***\n// @include https://example.com/rules/common.grl\n// *** END GRULES ***\n"
+	file := filepath.Join(workdir, "site.grl")
+
+	if err := os.WriteFile(file, []byte(site), 0600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	hash := sha256.Sum256([]byte(site))
+
+	s := settings{}
+	s.WildApricot.Rules.SHA256 = hex.EncodeToString(hash[:])
+
+	if _, err := loadRules(file, workdir, &s, false); err == nil {
+		t.Errorf("Expected error for unverified remote include, got %v", err)
+	}
+}
+
+func stashName(uri string, ext string) string {
+	hash := sha256.Sum256([]byte(uri))
+
+	return "wild-apricot-" + hex.EncodeToString(hash[:])[:16] + ext
+}
@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/uhppoted/uhppoted-lib/config"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/log"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/wild-apricot"
+)
+
+var LintRulesCmd = LintRules{
+	workdir:     DEFAULT_WORKDIR,
+	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	rules:       filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
+	members:     "",
+	doors:       "",
+	strict:      false,
+	debug:       false,
+}
+
+type LintRules struct {
+	workdir     string
+	credentials string
+	rules       string
+	members     string
+	doors       string
+	strict      bool
+	debug       bool
+}
+
+func (cmd *LintRules) Name() string {
+	return "lint-rules"
+}
+
+func (cmd *LintRules) Description() string {
+	return "Checks the ACL rules for unknown doors, groups, membership levels and functions"
+}
+
+func (cmd *LintRules) Usage() string {
+	return "--credentials <file> --rules <url>"
+}
+
+func (cmd *LintRules) Help() {
+	fmt.Println()
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] lint-rules [--credentials <file>] [--rules <url>] [--members <file>] [--doors <file>] [--strict]\n", APP)
+	fmt.Println()
+	fmt.Println("  Checks the ACL rules file for doors that are not configured, groups and membership levels that are")
+	fmt.Println("  not defined in the Wild Apricot account, unknown member and permissions functions and rules that")
+	fmt.Println("  do not retract themselves")
+	fmt.Println()
+
+	helpOptions(cmd.FlagSet())
+
+	fmt.Println()
+	fmt.Println("  Examples:")
+	fmt.Println(`    uhppote-app-wild-apricot lint-rules --credentials ".credentials/wild-apricot.json" \`)
+	fmt.Println(`                                        --rules "wild-apricot.grl"`)
+	fmt.Println()
+}
+
+func (cmd *LintRules) FlagSet() *flag.FlagSet {
+	flagset := flag.NewFlagSet("lint-rules", flag.ExitOnError)
+
+	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
+	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file. Defaults to "+cmd.credentials)
+	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file (.grl) policy file (.yaml or .json) or CEL rules file (.cel). Support file path, HTTP, HTTPS and git+https/git+file and comma separated lists of URIs. Defaults to "+cmd.rules)
+	flagset.StringVar(&cmd.members, "members", cmd.members, "Optional members list fixture (as for test-rules) for the groups and membership levels. Defaults to the Wild Apricot account")
+	flagset.StringVar(&cmd.doors, "doors", cmd.doors, "Optional door list file (one door per line). Defaults to the doors in the uhppoted.conf file")
+	flagset.BoolVar(&cmd.strict, "strict", cmd.strict, "Fails with an error if the rules file has any warnings")
+
+	return flagset
+}
+
+func (cmd *LintRules) Execute(args ...any) error {
+	options := args[0].(*Options)
+
+	cmd.debug = options.Debug
+
+	log.SetDebug(options.Debug)
+
+	// ... check parameters
+	if strings.TrimSpace(cmd.rules) == "" {
+		return fmt.Errorf("invalid rules file")
+	}
+
+	if cmd.members == "" && strings.TrimSpace(cmd.credentials) == "" {
+		return fmt.Errorf("invalid credentials file")
+	}
+
+	// ... get config, rules and vocabulary
+	conf := config.NewConfig()
+	if err := conf.Load(options.Config); err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	settings, err := getSettings(options.Config, conf)
+	if err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	rules, err := getRules(cmd.rules, cmd.workdir, settings, cmd.debug)
+	if err != nil {
+		return err
+	}
+
+	vocabulary := acl.Vocabulary{}
+
+	if cmd.doors != "" {
+		vocabulary.Doors, err = readDoors(cmd.doors)
+	} else {
+		vocabulary.Doors, err = getDoors(conf)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	vocabulary.Zones = settings.zones
+	vocabulary.Controllers = settings.controllers
+
+	if cmd.members != "" {
+		if members, err := getMembersFixture(cmd.members); err != nil {
+			return err
+		} else {
+			vocabulary.Groups, vocabulary.Memberships = fixtureVocabulary(members)
+		}
+	} else if credentials, err := getCredentials(cmd.credentials); err != nil {
+		return err
+	} else if vocabulary.Groups, vocabulary.Memberships, err = getVocabulary(conf, credentials); err != nil {
+		return err
+	}
+
+	// ... lint
+	warnings := rules.Lint(vocabulary)
+
+	for _, w := range warnings {
+		warnf("%v", w)
+	}
+
+	if len(warnings) == 0 {
+		infof("No problems found in rules file %v", cmd.rules)
+	} else if cmd.strict {
+		return fmt.Errorf("%v warnings in rules file %v", len(warnings), cmd.rules)
+	}
+
+	return nil
+}
+
+// Retrieves the member groups and membership levels from the Wild Apricot account.
+func getVocabulary(conf *config.Config, credentials *credentials) ([]types.Group, []types.Membership, error) {
+	timeout := conf.WildApricot.HTTP.ClientTimeout
+
+	token, err := wildapricot.Authorize(credentials.APIKey, timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	api := wildapricot.API{
+		Timeout: conf.WildApricot.HTTP.ClientTimeout,
+		Retries: conf.WildApricot.HTTP.Retries,
+		Delay:   conf.WildApricot.HTTP.RetryDelay,
+
+		PageSize:  conf.WildApricot.HTTP.PageSize,
+		PageDelay: conf.WildApricot.HTTP.PageDelay,
+		MaxPages:  conf.WildApricot.HTTP.MaxPages,
+	}
+
+	memberGroups, err := wildapricot.GetMemberGroups(credentials.AccountID, token, api)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	levels, err := wildapricot.GetMembershipLevels(credentials.AccountID, token, api)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	groups := []types.Group{}
+	for _, g := range memberGroups {
+		if group, err := types.NewGroup(g); err != nil {
+			return nil, nil, err
+		} else {
+			groups = append(groups, group)
+		}
+	}
+
+	memberships := []types.Membership{}
+	for _, l := range levels {
+		memberships = append(memberships, types.Membership{
+			ID:   l.ID,
+			Name: l.Name,
+		})
+	}
+
+	return groups, memberships, nil
+}
+
+// Returns the member groups and membership levels in a members list fixture.
+func fixtureVocabulary(members *types.Members) ([]types.Group, []types.Membership) {
+	memberships := []types.Membership{}
+	set := map[string]bool{}
+
+	for _, m := range members.Members {
+		if k := normalise(m.Membership.Name); k != "" && !set[k] {
+			set[k] = true
+			memberships = append(memberships, m.Membership)
+		}
+	}
+
+	return members.Groups, memberships
+}
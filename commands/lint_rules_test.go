@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+func TestFixtureVocabulary(t *testing.T) {
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	expected := []types.Membership{
+		types.Membership{Name: "Staff"},
+		types.Membership{Name: "Student"},
+		types.Membership{Name: "Alumni"},
+	}
+
+	groups, memberships := fixtureVocabulary(members)
+
+	if len(groups) != 2 || groups[0].Name != "Staff" || groups[1].Name != "Gryffindor" {
+		t.Errorf("Incorrect groups - expected:%v, got:%v", []string{"Staff", "Gryffindor"}, groups)
+	}
+
+	if !reflect.DeepEqual(memberships, expected) {
+		t.Errorf("Incorrect membership levels - expected:%v, got:%v", expected, memberships)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,29 +21,31 @@ import (
 )
 
 var LoadACLCmd = LoadACL{
-	workdir:     DEFAULT_WORKDIR,
-	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
-	rules:       filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
-	withPIN:     false,
-	force:       false,
-	strict:      false,
-	dryrun:      false,
-	lockfile:    "",
-	debug:       false,
+	workdir:      DEFAULT_WORKDIR,
+	credentials:  filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	rules:        filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
+	withPIN:      false,
+	withMemberID: false,
+	force:        false,
+	strict:       false,
+	dryrun:       false,
+	lockfile:     "",
+	debug:        false,
 }
 
 type LoadACL struct {
-	workdir     string
-	credentials string
-	rules       string
-	withPIN     bool
-	force       bool
-	strict      bool
-	dryrun      bool
-	logfile     string
-	rptfile     string
-	lockfile    string
-	debug       bool
+	workdir      string
+	credentials  string
+	rules        string
+	withPIN      bool
+	withMemberID bool
+	force        bool
+	strict       bool
+	dryrun       bool
+	logfile      string
+	rptfile      string
+	lockfile     string
+	debug        bool
 }
 
 func (cmd *LoadACL) Name() string {
@@ -59,7 +62,7 @@ func (cmd *LoadACL) Usage() string {
 
 func (cmd *LoadACL) Help() {
 	fmt.Println()
-	fmt.Printf("  Usage: %s [--debug] [--config <file>] load-acl [--credentials <file>] [--rules <url>] [--log <file>]\n", APP)
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] load-acl [--credentials <file>] [--rules <url>] [--with-member-id] [--log <file>]\n", APP)
 	fmt.Println()
 	fmt.Println("  Downloads an access control list from a Wild Apricot member database, applies the ACL rules and updates the card lists")
 	fmt.Println("  on the configured controllers")
@@ -79,10 +82,11 @@ func (cmd *LoadACL) FlagSet() *flag.FlagSet {
 
 	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
 	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file. Defaults to "+cmd.credentials)
-	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file. Support file path, HTTP and HTTPS. Defaults to "+cmd.rules)
+	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file (.grl) policy file (.yaml or .json) or CEL rules file (.cel). Support file path, HTTP, HTTPS and git+https/git+file and comma separated lists of URIs. Defaults to "+cmd.rules)
 	flagset.BoolVar(&cmd.withPIN, "with-pin", cmd.withPIN, "Updates the card keypad PIN code on the access controllers")
-	flagset.BoolVar(&cmd.force, "force", cmd.force, "Forces an update, overriding the  version and compare logic")
-	flagset.BoolVar(&cmd.strict, "strict", cmd.strict, "Fails with an error if the members list contains duplicate card numbers")
+	flagset.BoolVar(&cmd.withMemberID, "with-member-id", cmd.withMemberID, "Include the Wild Apricot member ID in the detail report")
+	flagset.BoolVar(&cmd.force, "force", cmd.force, "Forces an update, overriding the version and compare logic, the safety limits and the member count sanity check")
+	flagset.BoolVar(&cmd.strict, "strict", cmd.strict, "Fails with an error if the members list contains duplicate card numbers, the ACL references missing or mismatched time profiles, or a card conflicts across sites")
 	flagset.BoolVar(&cmd.dryrun, "dry-run", cmd.dryrun, "Simulates a load-acl without making any changes to the access controllers")
 	flagset.StringVar(&cmd.logfile, "log", cmd.logfile, "File to which the (optional) summary report is appended")
 	flagset.StringVar(&cmd.rptfile, "report", cmd.rptfile, "File to which the detail report is written. Defaults to stdout if not provided")
@@ -134,6 +138,11 @@ func (cmd *LoadACL) Execute(args ...any) error {
 		return fmt.Errorf("could not load configuration (%v)", err)
 	}
 
+	settings, err := getSettings(options.Config, conf)
+	if err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
 	credentials, err := getCredentials(cmd.credentials)
 	if err != nil {
 		return err
@@ -164,10 +173,21 @@ func (cmd *LoadACL) Execute(args ...any) error {
 		}
 	}
 
-	// ... get rules
-	rules, err := getRules(cmd.rules, cmd.workdir, cmd.debug)
-	if err != nil {
-		return fmt.Errorf("failed to load ruleset (%v)", err)
+	// ... sanity check
+	counts := countMembers(*members)
+	if failures := sanityCheck(version.Counts, counts, settings.maxDrop); len(failures) > 0 {
+		reasons := []string{}
+		for _, f := range failures {
+			reasons = append(reasons, f.String())
+		}
+
+		err := fmt.Errorf("sanity check failed: %v", strings.Join(reasons, ", "))
+		if !cmd.force {
+			cmd.appendReport("Sanity check failed", sanityTable(failures, settings.maxDrop))
+			return fmt.Errorf("%v - use --force to load the ACL", err)
+		}
+
+		warnf("%v (overridden by --force)", err)
 	}
 
 	// ... updated?
@@ -178,79 +198,94 @@ func (cmd *LoadACL) Execute(args ...any) error {
 		warnf("Unable to get DB version information (%v)", err)
 	}
 
-	// ... make ACL
-	doors, err := getDoors(conf)
+	// ... get sites
+	u, devices := getDevices(conf, cmd.debug)
+
+	sites, err := getSites(settings.Sites, cmd.rules, devices)
 	if err != nil {
 		return err
 	}
 
-	if cmd.debug {
-		filename := time.Now().Format("DOORS 2006-01-02 15:04:05.txt")
-		path := filepath.Join(os.TempDir(), filename)
-		if f, err := os.Create(path); err != nil {
-			fmt.Printf("ERROR %v", err)
-		} else {
-			for _, d := range doors {
-				fmt.Fprintf(f, "  %v\n", d)
-			}
-			f.Close()
-			fmt.Printf("DEBUG stashed doors list in file %s\n", path)
+	// ... make ACLs
+	ruleset := composite{}
+	ACLs := composite{}
+	tables := []*lib.Table{}
+	profiles := []map[string][]uint8{}
+
+	for _, site := range sites {
+		rules, ACL, err := cmd.makeACL(conf, settings, site, *members, u)
+		if err != nil {
+			return err
 		}
+
+		ruleset = append(ruleset, rules)
+		ACLs = append(ACLs, ACL)
+		tables = append(tables, cmd.asTable(ACL))
+
+		profiles = append(profiles, ACL.Profiles())
 	}
 
-	makeACL := func(members types.Members, doors []string) (*acl.ACL, error) {
-		if cmd.withPIN {
-			return rules.MakeACLWithPIN(members, doors)
-		} else {
-			return rules.MakeACL(members, doors)
+	if conflicts := siteConflicts(sites, tables); len(conflicts) > 0 {
+		for _, c := range conflicts {
+			warnf("%v", c)
 		}
-	}
 
-	asTable := func(a *acl.ACL) *lib.Table {
-		if cmd.withPIN {
-			return a.AsTableWithPIN()
-		} else {
-			return a.AsTable()
+		if cmd.strict {
+			return fmt.Errorf("card conflicts across sites")
 		}
 	}
 
-	ACL, err := makeACL(*members, doors)
-	if err != nil {
-		return err
+	// ... create/update schedule time profiles (independently of the ACL so that schedule changes are
+	//     applied even if the members and rules are unchanged)
+	if err := putTimeProfiles(u, devices, settings.schedules, cmd.dryrun); err != nil {
+		return fmt.Errorf("failed to update schedule time profiles (%v)", err)
 	}
 
-	if cmd.debug {
-		filename := time.Now().Format("ACL 2006-01-02 15:04:05.tsv")
-		path := filepath.Join(os.TempDir(), filename)
-		if f, err := os.Create(path); err != nil {
-			fmt.Printf("ERROR %v", err)
-		} else {
-			fmt.Fprintf(f, "%s\n", string(asTable(ACL).MarshalTextIndent("  ", " ")))
-			f.Close()
-			fmt.Printf("DEBUG stashed Wild Apricot ACL in file %s\n", path)
+	rulesUpdated := version.Hashes.Rules == "" || version.Hashes.Rules != ruleset.Hash()
+
+	if !cmd.force && !updated && !members.Updated(version.Hashes.Members, cmd.withPIN) && !rulesUpdated {
+		infof("Nothing to do")
+		return nil
+	}
+
+	for i, site := range sites {
+		if err := checkTimeProfiles(u, site.Devices, profiles[i], settings.schedules, nil, cmd.strict, cmd.dryrun); err != nil {
+			return err
+		}
+	}
+
+	if !settings.limits.IsZero() {
+		if err := cmd.checkSafetyLimits(u, sites, tables, settings.limits, *members); err != nil {
+			return err
 		}
 	}
 
 	// ... load
-	u, devices := getDevices(conf, cmd.debug)
-	cards := asTable(ACL)
+	rpt := map[uint32]lib.Report{}
+	warnings := []error{}
+	changes := []auditChange{}
 
-	// different, err := cmd.compare(&u, devices, cards)
-	// if err != nil {
-	// 	return err
-	// }
+	for i, site := range sites {
+		if len(sites) > 1 {
+			infof("Loading ACL for site %v", site)
+		}
 
-	if !cmd.force && !updated && !members.Updated(version.Hashes.Members, cmd.withPIN) && !rules.Updated(version.Hashes.Rules) {
-		infof("Nothing to do")
-		return nil
-	}
+		previous, errors := lib.GetACL(u, site.Devices)
+		if len(errors) > 0 {
+			warnf("Unable to retrieve current ACL from controllers for audit store (%v)", errors)
+		}
 
-	rpt, warnings, err := cmd.load(u, devices, cards)
-	if err != nil {
-		return err
+		r, w, err := cmd.load(u, site.Devices, tables[i])
+		if err != nil {
+			return err
+		}
+
+		maps.Copy(rpt, r)
+		warnings = append(warnings, w...)
+		changes = append(changes, auditChanges(r, tables[i], previous, site.Devices, *members)...)
 	}
 
-	if rpt != nil {
+	if len(rpt) > 0 {
 		if err := cmd.log(rpt, warnings); err != nil {
 			warnf("Error appending summary report to log file (%v)", err)
 		}
@@ -260,23 +295,113 @@ func (cmd *LoadACL) Execute(args ...any) error {
 		}
 	}
 
+	var hashable Hashable = members
 	if cmd.withPIN {
-		membersWithPIN := types.MembersWithPIN{
+		hashable = &types.MembersWithPIN{
 			Members: *members,
 		}
+	}
+
+	latest := newVersionInfo(credentials.AccountID, timestamp, hashable, ruleset, ACLs)
+	latest.Counts = counts
 
-		if err := storeVersionInfo(cmd.workdir, credentials.AccountID, timestamp, &membersWithPIN, rules, ACL); err != nil {
-			return fmt.Errorf("failed to store updated version information (%v)", err)
+	// ... a dry run keeps the recorded counts so that the sanity check compares the next load with the
+	//     last ACL actually loaded
+	if cmd.dryrun {
+		latest.Counts = version.Counts
+	}
+
+	if !cmd.dryrun {
+		record := auditRecord{
+			versionInfo: latest,
+			Changes:     changes,
 		}
-	} else {
-		if err := storeVersionInfo(cmd.workdir, credentials.AccountID, timestamp, members, rules, ACL); err != nil {
-			return fmt.Errorf("failed to store updated version information (%v)", err)
+
+		if err := appendAudit(cmd.workdir, record); err != nil {
+			warnf("Error appending to audit store (%v)", err)
 		}
+
+		snapshot := newSnapshot(record.versionInfo, cmd.withPIN, sites, tables)
+		if err := storeSnapshot(cmd.workdir, snapshot); err != nil {
+			warnf("Error saving ACL snapshot (%v)", err)
+		} else {
+			infof("Saved ACL snapshot %v", snapshot.ID)
+		}
+
+		if pruned, err := pruneSnapshots(cmd.workdir, credentials.AccountID, settings.snapshots); err != nil {
+			warnf("Error deleting old ACL snapshots (%v)", err)
+		} else if len(pruned) > 0 {
+			infof("Deleted %v old ACL snapshots (%v)", len(pruned), strings.Join(pruned, ", "))
+		}
+	}
+
+	if err := storeVersionInfo(cmd.workdir, latest); err != nil {
+		return fmt.Errorf("failed to store updated version information (%v)", err)
 	}
 
 	return nil
 }
 
+// Generates the ACL for a site from the site rules file and the doors on the site controllers.
+// The rules files for named sites are stashed in the sites/<name> subdirectory of the workdir.
+func (cmd *LoadACL) makeACL(conf *config.Config, settings *settings, site site, members types.Members, u uhppote.IUHPPOTE) (*acl.Rules, *acl.ACL, error) {
+	rules, doors, err := getSiteRules(conf, settings, site, cmd.workdir, cmd.debug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cmd.debug {
+		filename := time.Now().Format("DOORS 2006-01-02 15:04:05.txt")
+		path := filepath.Join(os.TempDir(), filename)
+		if f, err := os.Create(path); err != nil {
+			fmt.Printf("ERROR %v", err)
+		} else {
+			for _, d := range doors {
+				fmt.Fprintf(f, "  %v\n", d)
+			}
+			f.Close()
+			fmt.Printf("DEBUG stashed doors list in file %s\n", path)
+		}
+	}
+
+	getPreviousACL(rules, settings, u, site.Devices)
+
+	var ACL *acl.ACL
+	if cmd.withPIN {
+		ACL, err = rules.MakeACLWithPIN(members, doors)
+	} else {
+		ACL, err = rules.MakeACL(members, doors)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logFailures(ACL, settings.evaluation.OnError)
+
+	if cmd.debug {
+		filename := time.Now().Format("ACL 2006-01-02 15:04:05.tsv")
+		path := filepath.Join(os.TempDir(), filename)
+		if f, err := os.Create(path); err != nil {
+			fmt.Printf("ERROR %v", err)
+		} else {
+			fmt.Fprintf(f, "%s\n", string(cmd.asTable(ACL).MarshalTextIndent("  ", " ")))
+			f.Close()
+			fmt.Printf("DEBUG stashed Wild Apricot ACL in file %s\n", path)
+		}
+	}
+
+	return rules, ACL, nil
+}
+
+func (cmd *LoadACL) asTable(a *acl.ACL) *lib.Table {
+	if cmd.withPIN {
+		return a.AsTableWithPIN()
+	} else {
+		return a.AsTable()
+	}
+}
+
 // func (cmd *LoadACL) lock() (string, error) {
 // 	lockfile := filepath.Join(cmd.workdir, ".wild-apricot", "uhppoted-app-wild-apricot.lock")
 // 	pid := fmt.Sprintf("%d\n", os.Getpid())
@@ -373,6 +498,69 @@ func (cmd *LoadACL) load(u uhppote.IUHPPOTE, devices []uhppote.Device, cards *li
 // 	return false, nil
 // }
 
+// Compares the new ACL with the cards on the controllers and fails the load if the card deletions or
+// revocations exceed the safety limits (unless --force), reporting the blocked changes.
+func (cmd *LoadACL) checkSafetyLimits(u uhppote.IUHPPOTE, sites []site, tables []*lib.Table, limits safetyLimits, members types.Members) error {
+	changes := []safetyChange{}
+	cards := 0
+
+	for i, site := range sites {
+		current, errors := lib.GetACL(u, site.Devices)
+		if len(errors) > 0 {
+			return fmt.Errorf("%v", errors)
+		}
+
+		acl, _, err := lib.ParseTable(tables[i], site.Devices, false)
+		if err != nil {
+			return err
+		} else if acl == nil {
+			return fmt.Errorf("error creating ACL for site %v", site)
+		}
+
+		for _, v := range current {
+			cards += len(v)
+		}
+
+		changes = append(changes, safetyChanges(current, *acl, site.Devices, members)...)
+	}
+
+	if err := limits.check(changes, cards); err == nil {
+		return nil
+	} else if cmd.force {
+		warnf("%v (overridden by --force)", err)
+		return nil
+	} else {
+		cmd.appendReport("Blocked changes", safetyTable(changes))
+
+		return fmt.Errorf("%v - use --force to load the ACL", err)
+	}
+}
+
+// Writes the reason a load was refused to the report file (or the console if no report file is
+// specified).
+func (cmd *LoadACL) appendReport(title string, table *lib.Table) {
+	title = fmt.Sprintf("%s %s", title, time.Now().Format("2006-01-02 15:04:05"))
+
+	var b bytes.Buffer
+	if strings.HasSuffix(cmd.rptfile, ".tsv") {
+		if err := table.ToTSV(&b); err != nil {
+			warnf("Error writing report file (%v)", err)
+			return
+		}
+	} else {
+		fmt.Fprintf(&b, "  %s\n\n%s\n", title, string(table.MarshalTextIndent("  ", " ")))
+	}
+
+	if cmd.rptfile == "" {
+		fmt.Printf("\n%s\n", b.String())
+	} else if f, err := os.OpenFile(cmd.rptfile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		warnf("Error writing report file (%v)", err)
+	} else {
+		fmt.Fprintf(f, "%s", b.String())
+		f.Close()
+	}
+}
+
 func (cmd *LoadACL) log(rpt map[uint32]lib.Report, warnings []error) error {
 	if cmd.logfile != "" {
 		var b bytes.Buffer
@@ -404,9 +592,11 @@ func (cmd *LoadACL) log(rpt map[uint32]lib.Report, warnings []error) error {
 func (cmd *LoadACL) report(rpt map[uint32]lib.Report, members types.Members) error {
 	// ... build card/name map
 	names := map[uint32]string{}
+	ids := map[uint32]uint32{}
 	for _, m := range members.Members {
 		if m.CardNumber != nil {
 			names[uint32(*m.CardNumber)] = m.Name
+			ids[uint32(*m.CardNumber)] = m.ID
 		}
 	}
 
@@ -419,6 +609,11 @@ func (cmd *LoadACL) report(rpt map[uint32]lib.Report, members types.Members) err
 		"name":       3,
 	}
 
+	if cmd.withMemberID {
+		header = append(header, "Member ID")
+		index["memberid"] = 4
+	}
+
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 
 	consolidated := lib.Consolidate(rpt)
@@ -459,6 +654,10 @@ func (cmd *LoadACL) report(rpt map[uint32]lib.Report, members types.Members) err
 				row[ix] = fmt.Sprintf("%v", names[card])
 			}
 
+			if ix, ok := index["memberid"]; ok {
+				row[ix] = memberID(ids[card])
+			}
+
 			rows = append(rows, row)
 		}
 	}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+)
+
+// Returns the output format (table, tsv or json), defaulting to the output file extension
+// (if any) or 'table'.
+func outputFormat(format string, file string) (string, error) {
+	f := strings.ToLower(strings.TrimSpace(format))
+
+	if f == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".tsv":
+			return "tsv", nil
+		case ".json":
+			return "json", nil
+		default:
+			return "table", nil
+		}
+	}
+
+	if !slices.Contains([]string{"table", "tsv", "json"}, f) {
+		return "", fmt.Errorf("invalid output format '%v'", format)
+	}
+
+	return f, nil
+}
+
+// Writes a list to the output file (or stdout if the file is blank) as a table, TSV or JSON. The
+// table is the tabular representation of the list.
+func output(list any, table *lib.Table, format string, file string) error {
+	var b bytes.Buffer
+
+	switch format {
+	case "json":
+		if encoded, err := json.MarshalIndent(list, "", "  "); err != nil {
+			return fmt.Errorf("error creating JSON file (%v)", err)
+		} else {
+			fmt.Fprintf(&b, "%s\n", encoded)
+		}
+
+	case "tsv":
+		if err := table.ToTSV(&b); err != nil {
+			return fmt.Errorf("error creating TSV file (%v)", err)
+		}
+
+	default:
+		fmt.Fprintln(&b, string(table.MarshalTextIndent("  ", " ")))
+	}
+
+	if file == "" {
+		fmt.Fprint(os.Stdout, b.String())
+		return nil
+	}
+
+	return write(file, b.Bytes())
+}
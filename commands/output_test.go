@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		file     string
+		expected string
+	}{
+		{"", "", "table"},
+		{"", "expiring.tsv", "tsv"},
+		{"", "expiring.json", "json"},
+		{"TSV", "", "tsv"},
+		{"table", "expiring.json", "table"},
+	}
+
+	for _, test := range tests {
+		if format, err := outputFormat(test.format, test.file); err != nil {
+			t.Errorf("Unexpected error (%v)", err)
+		} else if format != test.expected {
+			t.Errorf("Incorrect format for '%v' '%v' - expected:%v, got:%v", test.format, test.file, test.expected, format)
+		}
+	}
+
+	if _, err := outputFormat("xml", ""); err == nil {
+		t.Errorf("Expected error for invalid format, got %v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package commands
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -12,6 +15,40 @@ type Hashable interface {
 	Hash() string
 }
 
+// Revisioned is implemented by rules retrieved from a source with a revision e.g. a git
+// repository.
+type Revisioned interface {
+	Revision() string
+}
+
+// composite combines the hashes (and revisions) of the per-site rules and ACLs. A single
+// element composite is equivalent to the element.
+type composite []Hashable
+
+func (c composite) Hash() string {
+	if len(c) == 1 {
+		return c[0].Hash()
+	}
+
+	hash := sha256.New()
+	for _, v := range c {
+		hash.Write([]byte(v.Hash()))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (c composite) Revision() string {
+	revisions := []string{}
+	for _, v := range c {
+		if r, ok := v.(Revisioned); ok && r.Revision() != "" {
+			revisions = append(revisions, r.Revision())
+		}
+	}
+
+	return strings.Join(revisions, ",")
+}
+
 type versionInfo struct {
 	AccountID uint32     `json:"account-id"`
 	Timestamp *time.Time `json:"timestamp"`
@@ -20,6 +57,10 @@ type versionInfo struct {
 		Rules   string `json:"rules,omitempty"`
 		ACL     string `json:"acl,omitempty"`
 	} `json:"hashes"`
+	Revisions struct {
+		Rules string `json:"rules,omitempty"`
+	} `json:"revisions,omitzero"`
+	Counts memberCounts `json:"counts,omitzero"`
 }
 
 func getVersionInfo(workdir string, accountID uint32) versionInfo {
@@ -41,7 +82,23 @@ func getVersionInfo(workdir string, accountID uint32) versionInfo {
 	return v
 }
 
-func storeVersionInfo(workdir string, accountID uint32, timestamp time.Time, members, rules, acl Hashable) error {
+func storeVersionInfo(workdir string, v versionInfo) error {
+	bytes, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(workdir, ".wild-apricot", fmt.Sprintf("%v.version", v.AccountID))
+	bytes = append(bytes, []byte("\n")...)
+
+	if err := os.WriteFile(file, bytes, 0644); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func newVersionInfo(accountID uint32, timestamp time.Time, members, rules, acl Hashable) versionInfo {
 	v := versionInfo{
 		AccountID: accountID,
 		Timestamp: &timestamp,
@@ -56,17 +113,9 @@ func storeVersionInfo(workdir string, accountID uint32, timestamp time.Time, mem
 		},
 	}
 
-	bytes, err := json.MarshalIndent(v, "", "  ")
-	if err != nil {
-		return err
+	if r, ok := rules.(Revisioned); ok {
+		v.Revisions.Rules = r.Revision()
 	}
 
-	file := filepath.Join(workdir, ".wild-apricot", fmt.Sprintf("%v.version", accountID))
-	bytes = append(bytes, []byte("\n")...)
-
-	if err := os.WriteFile(file, bytes, 0644); err != nil {
-		return err
-	}
-
-	return nil
+	return v
 }
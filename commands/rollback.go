@@ -0,0 +1,367 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+	"github.com/uhppoted/uhppoted-lib/config"
+	"github.com/uhppoted/uhppoted-lib/lockfile"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var RollbackCmd = Rollback{
+	workdir:     DEFAULT_WORKDIR,
+	credentials: filepath.Join(DEFAULT_CONFIG_DIR, ".wild-apricot", "credentials.json"),
+	list:        false,
+	snapshot:    "",
+	strict:      false,
+	confirm:     false,
+	lockfile:    "",
+	debug:       false,
+}
+
+type Rollback struct {
+	workdir     string
+	credentials string
+	list        bool
+	snapshot    string
+	strict      bool
+	confirm     bool
+	lockfile    string
+	debug       bool
+}
+
+func (cmd *Rollback) Name() string {
+	return "rollback"
+}
+
+func (cmd *Rollback) Description() string {
+	return "Reloads an ACL snapshot saved by load-acl to the configured controllers"
+}
+
+func (cmd *Rollback) Usage() string {
+	return "--credentials <file> --list | --snapshot <ID|previous>"
+}
+
+func (cmd *Rollback) Help() {
+	fmt.Println()
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] rollback [--credentials <file>] [--list] [--snapshot <ID|previous>] [--confirm]\n", APP)
+	fmt.Println()
+	fmt.Println("  Lists the ACL snapshots saved by load-acl or compares the cards on the configured controllers with a snapshot")
+	fmt.Println("  and reloads the snapshot ACL (only with --confirm)")
+	fmt.Println()
+
+	helpOptions(cmd.FlagSet())
+
+	fmt.Println()
+	fmt.Println("  Examples:")
+	fmt.Println(`    uhppote-app-wild-apricot rollback --list`)
+	fmt.Println(`    uhppote-app-wild-apricot rollback --snapshot previous`)
+	fmt.Println(`    uhppote-app-wild-apricot --config uhppoted.conf rollback --snapshot 20260115-093000 --confirm`)
+	fmt.Println()
+}
+
+func (cmd *Rollback) FlagSet() *flag.FlagSet {
+	flagset := flag.NewFlagSet("rollback", flag.ExitOnError)
+	lockfile := filepath.Join(cmd.workdir, ".wild-apricot", "uhppoted-app-wild-apricot.lock")
+
+	flagset.StringVar(&cmd.workdir, "workdir", cmd.workdir, "Directory for working files (tokens, revisions, etc)'")
+	flagset.StringVar(&cmd.credentials, "credentials", cmd.credentials, "Path for the 'credentials.json' file (for the account ID). Defaults to "+cmd.credentials)
+	flagset.BoolVar(&cmd.list, "list", cmd.list, "Lists the ACL snapshots saved by load-acl")
+	flagset.StringVar(&cmd.snapshot, "snapshot", cmd.snapshot, "Snapshot ID (from --list) or 'previous' for the ACL loaded before the current ACL")
+	flagset.BoolVar(&cmd.strict, "strict", cmd.strict, "Fails with an error if the snapshot ACL contains duplicate card numbers")
+	flagset.BoolVar(&cmd.confirm, "confirm", cmd.confirm, "Reloads the snapshot ACL to the access controllers. Without --confirm the snapshot ACL is only compared with the controllers")
+	flagset.StringVar(&cmd.lockfile, "lockfile", cmd.lockfile, fmt.Sprintf("Filepath for lock file. Defaults to %v", lockfile))
+
+	return flagset
+}
+
+func (cmd *Rollback) Execute(args ...any) error {
+	timestamp := time.Now()
+	options := args[0].(*Options)
+
+	cmd.debug = options.Debug
+
+	// ... check parameters
+	if strings.TrimSpace(cmd.credentials) == "" {
+		return fmt.Errorf("invalid credentials file")
+	}
+
+	if !cmd.list && strings.TrimSpace(cmd.snapshot) == "" {
+		return fmt.Errorf("missing --snapshot (use --list to list the saved snapshots)")
+	}
+
+	credentials, err := getCredentials(cmd.credentials)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := listSnapshots(cmd.workdir, credentials.AccountID)
+	if err != nil {
+		return err
+	}
+
+	// ... list snapshots?
+	if cmd.list {
+		if len(snapshots) == 0 {
+			infof("No ACL snapshots")
+		} else {
+			fmt.Printf("%s\n", string(snapshotTable(snapshots).MarshalTextIndent("  ", " ")))
+		}
+
+		return nil
+	}
+
+	snapshot, err := findSnapshot(snapshots, cmd.snapshot)
+	if err != nil {
+		return err
+	}
+
+	// ... locked?
+	lockFile := config.Lockfile{
+		File:   filepath.Join(cmd.workdir, ".wild-apricot", "uhppoted-app-wild-apricot.lock"),
+		Remove: lockfile.RemoveLockfile,
+	}
+
+	if cmd.lockfile != "" {
+		lockFile = config.Lockfile{
+			File:   cmd.lockfile,
+			Remove: lockfile.RemoveLockfile,
+		}
+	}
+
+	if kraken, err := lockfile.MakeLockFile(lockFile); err != nil {
+		return err
+	} else {
+		defer func() {
+			infof("Removing lockfile '%v'", lockFile.File)
+			kraken.Release()
+		}()
+	}
+
+	// ... get config
+	conf := config.NewConfig()
+	if err := conf.Load(options.Config); err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	if _, err := getSettings(options.Config, conf); err != nil {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	u, devices := getDevices(conf, cmd.debug)
+
+	// ... resolve, parse and compare every site before loading any of them
+	infof("Rolling back to ACL snapshot %v", snapshot.ID)
+
+	type rollback struct {
+		table       *lib.Table
+		controllers []uhppote.Device
+		acl         lib.ACL
+		current     lib.ACL
+	}
+
+	rollbacks := []rollback{}
+
+	for _, site := range snapshot.Sites {
+		table, controllers, err := site.resolve(devices)
+		if err != nil {
+			return fmt.Errorf("snapshot %v: %v", snapshot.ID, err)
+		}
+
+		ACL, warnings, err := lib.ParseTable(table, controllers, cmd.strict)
+		if err != nil {
+			return err
+		} else if ACL == nil {
+			return fmt.Errorf("error creating ACL from snapshot %v", snapshot.ID)
+		}
+
+		for _, w := range warnings {
+			warnf("%v", w.Error())
+		}
+
+		current, diff, err := cmd.compare(u, controllers, *ACL, snapshot.WithPIN)
+		if err != nil {
+			return err
+		}
+
+		cmd.show(snapshot.ID, site.Name, diff)
+
+		rollbacks = append(rollbacks, rollback{
+			table:       table,
+			controllers: controllers,
+			acl:         *ACL,
+			current:     current,
+		})
+	}
+
+	if !cmd.confirm {
+		infof("Dry run only - use --confirm to reload ACL snapshot %v", snapshot.ID)
+		return nil
+	}
+
+	// ... reload each site
+	rpt := map[uint32]lib.Report{}
+	changes := []auditChange{}
+
+	var loadErr error
+	for i, r := range rollbacks {
+		if len(snapshot.Sites) > 1 {
+			infof("Rolling back ACL for site %v", snapshot.Sites[i].Name)
+		}
+
+		loaded, err := cmd.load(u, r.acl, snapshot.WithPIN)
+		if err != nil {
+			loadErr = err
+			break
+		}
+
+		maps.Copy(rpt, loaded)
+		changes = append(changes, auditChanges(loaded, r.table, r.current, r.controllers, types.Members{})...)
+	}
+
+	// ... the version information is updated to the snapshot so that the next load-acl reloads the
+	//     ACL if the current members or rules differ from the snapshot. The rules hash is cleared if
+	//     the rollback failed partway so that the next load-acl reloads every site.
+	version := snapshot.versionInfo
+	version.Timestamp = &timestamp
+
+	if loadErr != nil {
+		version.Hashes.Rules = ""
+	}
+
+	if loadErr == nil || len(changes) > 0 || len(rpt) > 0 {
+		record := auditRecord{
+			versionInfo: version,
+			Rollback:    snapshot.ID,
+			Changes:     changes,
+		}
+
+		if err := appendAudit(cmd.workdir, record); err != nil {
+			warnf("Error appending to audit store (%v)", err)
+		}
+	}
+
+	if err := storeVersionInfo(cmd.workdir, version); err != nil {
+		return fmt.Errorf("failed to store updated version information (%v)", err)
+	}
+
+	return loadErr
+}
+
+// Returns the current ACL on the controllers and the differences from the snapshot ACL.
+func (cmd *Rollback) compare(u uhppote.IUHPPOTE, devices []uhppote.Device, acl lib.ACL, withPIN bool) (lib.ACL, lib.SystemDiff, error) {
+	current, errors := lib.GetACL(u, devices)
+	if len(errors) > 0 {
+		return nil, nil, fmt.Errorf("%v", errors)
+	}
+
+	compare := func(current, acl lib.ACL) (map[uint32]lib.Diff, error) {
+		if withPIN {
+			return lib.CompareWithPIN(current, acl)
+		} else {
+			return lib.Compare(current, acl)
+		}
+	}
+
+	diff, err := compare(current, acl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return current, lib.SystemDiff(diff), nil
+}
+
+func (cmd *Rollback) show(id string, site string, diff lib.SystemDiff) {
+	title := fmt.Sprintf("ACL Rollback Report %s (snapshot %v)", time.Now().Format("2006-01-02 15:04:05"), id)
+	if site != "" {
+		title += fmt.Sprintf(" site %v", site)
+	}
+
+	fmt.Println()
+	fmt.Printf("  %s\n", title)
+	fmt.Println()
+
+	if !diff.HasChanges() {
+		fmt.Printf("%v\n", "  NO DIFFERENCES")
+	} else {
+		fmt.Printf("%v\n", string(summarize(diff).MarshalTextIndent("  ", " ")))
+	}
+
+	fmt.Println()
+}
+
+func (cmd *Rollback) load(u uhppote.IUHPPOTE, acl lib.ACL, withPIN bool) (map[uint32]lib.Report, error) {
+	putACL := func(acl lib.ACL) (map[uint32]lib.Report, []error) {
+		if withPIN {
+			return lib.PutACLWithPIN(u, acl, false)
+		} else {
+			return lib.PutACL(u, acl, false)
+		}
+	}
+
+	rpt, errors := putACL(acl)
+	if len(errors) > 0 {
+		return nil, fmt.Errorf("%v", errors)
+	}
+
+	for k, v := range rpt {
+		for _, err := range v.Errors {
+			errorf("%v  %v", k, err)
+		}
+	}
+
+	summary := lib.Summarize(rpt)
+	format := "%v  unchanged:%v  updated:%v  added:%v  deleted:%v  failed:%v  errors:%v"
+	for _, v := range summary {
+		infof(format, v.DeviceID, v.Unchanged, v.Updated, v.Added, v.Deleted, v.Failed, v.Errored)
+	}
+
+	return rpt, nil
+}
+
+func snapshotTable(snapshots []snapshot) *lib.Table {
+	table := lib.Table{
+		Header:  []string{"Snapshot", "Timestamp", "Sites", "Cards", "PIN", "Rules"},
+		Records: [][]string{},
+	}
+
+	for _, s := range snapshots {
+		timestamp := ""
+		if s.Timestamp != nil {
+			timestamp = s.Timestamp.Format("2006-01-02 15:04:05")
+		}
+
+		rules := s.Revisions.Rules
+		if rules == "" {
+			rules = s.Hashes.Rules
+		}
+
+		if len(rules) > 12 {
+			rules = rules[:12]
+		}
+
+		pin := "N"
+		if s.WithPIN {
+			pin = "Y"
+		}
+
+		table.Records = append(table.Records, []string{
+			s.ID,
+			timestamp,
+			fmt.Sprintf("%v", len(s.Sites)),
+			fmt.Sprintf("%v", s.cards()),
+			pin,
+			rules,
+		})
+	}
+
+	return &table
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// safetyLimit is the maximum number of card deletions (or revocations) in a single load-acl run,
+// either as an absolute number (e.g. 50) or as a percentage of the cards on the controllers
+// (e.g. 10%). A zero limit is unlimited.
+type safetyLimit struct {
+	Count   uint
+	Percent float64
+}
+
+// safetyLimits are the configured load-acl limits (wild-apricot.acl.max-deletions and
+// wild-apricot.acl.max-revocations).
+type safetyLimits struct {
+	Deletions   safetyLimit
+	Revocations safetyLimit
+}
+
+// safetyChange is a card deletion or revocation that would be made by a load-acl, with the
+// doors revoked (for revocations).
+type safetyChange struct {
+	Controller uint32
+	CardNumber uint32
+	MemberID   uint32
+	Name       string
+	Action     string
+	Doors      []string
+}
+
+func parseSafetyLimit(s string) (safetyLimit, error) {
+	s = strings.TrimSpace(s)
+
+	if s == "" {
+		return safetyLimit{}, nil
+	}
+
+	if v, ok := strings.CutSuffix(s, "%"); ok {
+		if p, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil || p <= 0 || p > 100 {
+			return safetyLimit{}, fmt.Errorf("invalid percentage '%v'", s)
+		} else {
+			return safetyLimit{Percent: p}, nil
+		}
+	}
+
+	if N, err := strconv.ParseUint(s, 10, 32); err != nil {
+		return safetyLimit{}, fmt.Errorf("invalid limit '%v'", s)
+	} else {
+		return safetyLimit{Count: uint(N)}, nil
+	}
+}
+
+func (l safetyLimit) IsZero() bool {
+	return l.Count == 0 && l.Percent == 0
+}
+
+// Returns true if the number of changes exceeds the limit for the number of cards on the controllers.
+func (l safetyLimit) exceeded(changes int, cards int) bool {
+	switch {
+	case l.Count > 0:
+		return changes > int(l.Count)
+
+	case l.Percent > 0 && cards > 0:
+		return 100.0*float64(changes)/float64(cards) > l.Percent
+
+	default:
+		return false
+	}
+}
+
+func (l safetyLimit) String() string {
+	switch {
+	case l.Count > 0:
+		return fmt.Sprintf("%v", l.Count)
+
+	case l.Percent > 0:
+		return fmt.Sprintf("%v%%", l.Percent)
+
+	default:
+		return "unlimited"
+	}
+}
+
+func (l safetyLimits) IsZero() bool {
+	return l.Deletions.IsZero() && l.Revocations.IsZero()
+}
+
+// Returns the card deletions and door revocations required to update the current ACL on the
+// controllers to the new ACL. The door names are from the controller configuration.
+func safetyChanges(current, acl lib.ACL, devices []uhppote.Device, members types.Members) []safetyChange {
+	index := map[uint32]types.Member{}
+	for _, m := range members.Members {
+		if m.CardNumber != nil {
+			index[uint32(*m.CardNumber)] = m
+		}
+	}
+
+	doors := map[uint32][]string{}
+	for _, d := range devices {
+		doors[d.DeviceID] = d.Doors
+	}
+
+	changes := []safetyChange{}
+	for _, controller := range slices.Sorted(maps.Keys(current)) {
+		cards, ok := acl[controller]
+		if !ok {
+			continue
+		}
+
+		for _, card := range slices.Sorted(maps.Keys(current[controller])) {
+			change := safetyChange{
+				Controller: controller,
+				CardNumber: card,
+			}
+
+			if m, ok := index[card]; ok {
+				change.MemberID = m.ID
+				change.Name = m.Name
+			}
+
+			if c, ok := cards[card]; !ok {
+				change.Action = "delete"
+			} else {
+				for door := uint8(1); door <= 4; door++ {
+					if current[controller][card].Doors[door] != 0 && c.Doors[door] == 0 {
+						change.Doors = append(change.Doors, doorName(doors[controller], door))
+					}
+				}
+
+				if len(change.Doors) == 0 {
+					continue
+				}
+
+				change.Action = "revoke"
+			}
+
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+// Returns an error if the deletions or revocations exceed the safety limits for the number of
+// cards on the controllers.
+func (l safetyLimits) check(changes []safetyChange, cards int) error {
+	deletions := 0
+	revocations := 0
+	for _, c := range changes {
+		switch c.Action {
+		case "delete":
+			deletions++
+
+		case "revoke":
+			revocations++
+		}
+	}
+
+	errors := []string{}
+
+	if l.Deletions.exceeded(deletions, cards) {
+		errors = append(errors, fmt.Sprintf("%v of %v cards deleted (max. %v)", deletions, cards, l.Deletions))
+	}
+
+	if l.Revocations.exceeded(revocations, cards) {
+		errors = append(errors, fmt.Sprintf("%v of %v cards with revoked doors (max. %v)", revocations, cards, l.Revocations))
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("safety limits exceeded: %v", strings.Join(errors, ", "))
+	}
+
+	return nil
+}
+
+func safetyTable(changes []safetyChange) *lib.Table {
+	table := lib.Table{
+		Header:  []string{"Controller", "Action", "Card Number", "Member ID", "Name", "Doors"},
+		Records: [][]string{},
+	}
+
+	for _, c := range changes {
+		table.Records = append(table.Records, []string{
+			fmt.Sprintf("%v", c.Controller),
+			c.Action,
+			fmt.Sprintf("%v", c.CardNumber),
+			memberID(c.MemberID),
+			c.Name,
+			strings.Join(c.Doors, ", "),
+		})
+	}
+
+	return &table
+}
+
+func doorName(doors []string, door uint8) string {
+	if int(door) <= len(doors) {
+		if name := strings.TrimSpace(doors[door-1]); name != "" {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("door %v", door)
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	core "github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+)
+
+func TestParseSafetyLimit(t *testing.T) {
+	tests := []struct {
+		limit    string
+		expected safetyLimit
+		err      bool
+	}{
+		{"", safetyLimit{}, false},
+		{"50", safetyLimit{Count: 50}, false},
+		{"10%", safetyLimit{Percent: 10}, false},
+		{" 2.5 % ", safetyLimit{Percent: 2.5}, false},
+		{"0%", safetyLimit{}, true},
+		{"150%", safetyLimit{}, true},
+		{"-1", safetyLimit{}, true},
+		{"lots", safetyLimit{}, true},
+	}
+
+	for _, test := range tests {
+		limit, err := parseSafetyLimit(test.limit)
+		if test.err && err == nil {
+			t.Errorf("Expected error parsing safety limit '%v'", test.limit)
+		} else if !test.err && err != nil {
+			t.Errorf("Unexpected error parsing safety limit '%v' (%v)", test.limit, err)
+		} else if limit != test.expected {
+			t.Errorf("Incorrect safety limit for '%v'\n   expected:%v\n   got:     %v", test.limit, test.expected, limit)
+		}
+	}
+}
+
+func TestSafetyChanges(t *testing.T) {
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	card := func(number uint32, doors ...uint8) core.Card {
+		return core.Card{
+			CardNumber: number,
+			From:       core.MustParseDate("2026-01-01"),
+			To:         core.MustParseDate("2026-12-31"),
+			Doors:      map[uint8]uint8{1: doors[0], 2: doors[1], 3: doors[2], 4: doors[3]},
+		}
+	}
+
+	devices := []uhppote.Device{
+		{DeviceID: 405419896, Doors: []string{"Great Hall", "Gryffindor", "Dungeon", ""}},
+	}
+
+	current := lib.ACL{
+		405419896: {
+			1000001: card(1000001, 1, 1, 1, 0),
+			6000001: card(6000001, 1, 1, 0, 0),
+			2000001: card(2000001, 1, 0, 1, 1),
+		},
+	}
+
+	acl := lib.ACL{
+		405419896: {
+			1000001: card(1000001, 1, 0, 29, 0),
+			6000001: card(6000001, 1, 1, 1, 0),
+		},
+	}
+
+	expected := []safetyChange{
+		{Controller: 405419896, CardNumber: 1000001, MemberID: 1, Name: "Albus Dumbledore", Action: "revoke", Doors: []string{"Gryffindor"}},
+		{Controller: 405419896, CardNumber: 2000001, MemberID: 4, Name: "Tom Riddle", Action: "delete"},
+	}
+
+	changes := safetyChanges(current, acl, devices, *members)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Incorrect safety changes\n   expected:%v\n   got:     %v", expected, changes)
+	}
+
+	tests := []struct {
+		limits safetyLimits
+		err    bool
+	}{
+		{safetyLimits{}, false},
+		{safetyLimits{Deletions: safetyLimit{Count: 1}}, false},
+		{safetyLimits{Deletions: safetyLimit{Count: 1}, Revocations: safetyLimit{Count: 1}}, false},
+		{safetyLimits{Deletions: safetyLimit{Percent: 50}}, false},
+		{safetyLimits{Deletions: safetyLimit{Percent: 25}}, true},
+		{safetyLimits{Revocations: safetyLimit{Percent: 10}}, true},
+	}
+
+	for _, test := range tests {
+		if err := test.limits.check(changes, 3); test.err && err == nil {
+			t.Errorf("Expected safety limits %+v to be exceeded", test.limits)
+		} else if !test.err && err != nil {
+			t.Errorf("Unexpected error for safety limits %+v (%v)", test.limits, err)
+		}
+	}
+}
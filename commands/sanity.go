@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// memberCounts are the number of members, cards and member groups retrieved from Wild Apricot by
+// a load-acl, recorded in the version information for the sanity check on the next load-acl.
+type memberCounts struct {
+	Members int `json:"members"`
+	Cards   int `json:"cards"`
+	Groups  int `json:"groups"`
+}
+
+// sanityFailure is a member, card or group count that dropped by more than the configured limit
+// (wild-apricot.acl.max-drop) since the previous load-acl.
+type sanityFailure struct {
+	Count    string
+	Previous int
+	Current  int
+}
+
+func countMembers(members types.Members) memberCounts {
+	counts := memberCounts{
+		Members: len(members.Members),
+		Groups:  len(members.Groups),
+	}
+
+	for _, m := range members.Members {
+		if m.CardNumber != nil && *m.CardNumber != 0 {
+			counts.Cards++
+		}
+	}
+
+	return counts
+}
+
+// Returns the member, card and group counts that dropped by more than the limit since the previous
+// load-acl. Counts that were not recorded for the previous load-acl are not checked.
+func sanityCheck(previous, current memberCounts, limit safetyLimit) []sanityFailure {
+	failures := []sanityFailure{}
+
+	counts := []struct {
+		count    string
+		previous int
+		current  int
+	}{
+		{"members", previous.Members, current.Members},
+		{"cards", previous.Cards, current.Cards},
+		{"groups", previous.Groups, current.Groups},
+	}
+
+	for _, c := range counts {
+		if c.previous > 0 && c.current < c.previous && limit.exceeded(c.previous-c.current, c.previous) {
+			failures = append(failures, sanityFailure{
+				Count:    c.count,
+				Previous: c.previous,
+				Current:  c.current,
+			})
+		}
+	}
+
+	return failures
+}
+
+func (f sanityFailure) String() string {
+	drop := 100.0 * float64(f.Previous-f.Current) / float64(f.Previous)
+
+	return fmt.Sprintf("%v dropped from %v to %v (%.1f%%)", f.Count, f.Previous, f.Current, drop)
+}
+
+func sanityTable(failures []sanityFailure, limit safetyLimit) *lib.Table {
+	table := lib.Table{
+		Header:  []string{"Count", "Previous", "Current", "Drop", "Limit"},
+		Records: [][]string{},
+	}
+
+	for _, f := range failures {
+		table.Records = append(table.Records, []string{
+			f.Count,
+			fmt.Sprintf("%v", f.Previous),
+			fmt.Sprintf("%v", f.Current),
+			fmt.Sprintf("%.1f%%", 100.0*float64(f.Previous-f.Current)/float64(f.Previous)),
+			fmt.Sprintf("%v", limit),
+		})
+	}
+
+	return &table
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCountMembers(t *testing.T) {
+	members, err := getMembersFixture("test_members.json")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	expected := memberCounts{
+		Members: len(members.Members),
+		Cards:   len(members.Members),
+		Groups:  len(members.Groups),
+	}
+
+	if counts := countMembers(*members); counts != expected {
+		t.Errorf("Incorrect member counts\n   expected:%v\n   got:     %v", expected, counts)
+	}
+}
+
+func TestSanityCheck(t *testing.T) {
+	previous := memberCounts{Members: 120, Cards: 100, Groups: 8}
+
+	tests := []struct {
+		current  memberCounts
+		limit    safetyLimit
+		expected []sanityFailure
+	}{
+		{
+			current:  memberCounts{Members: 125, Cards: 102, Groups: 8},
+			limit:    safetyLimit{Percent: 50},
+			expected: []sanityFailure{},
+		},
+		{
+			current:  memberCounts{Members: 80, Cards: 60, Groups: 8},
+			limit:    safetyLimit{Percent: 50},
+			expected: []sanityFailure{},
+		},
+		{
+			current: memberCounts{Members: 0, Cards: 0, Groups: 0},
+			limit:   safetyLimit{Percent: 50},
+			expected: []sanityFailure{
+				{Count: "members", Previous: 120, Current: 0},
+				{Count: "cards", Previous: 100, Current: 0},
+				{Count: "groups", Previous: 8, Current: 0},
+			},
+		},
+		{
+			current: memberCounts{Members: 100, Cards: 60, Groups: 8},
+			limit:   safetyLimit{Count: 25},
+			expected: []sanityFailure{
+				{Count: "cards", Previous: 100, Current: 60},
+			},
+		},
+		{
+			current:  memberCounts{Members: 0, Cards: 0, Groups: 0},
+			limit:    safetyLimit{Percent: 100},
+			expected: []sanityFailure{},
+		},
+	}
+
+	for _, test := range tests {
+		if failures := sanityCheck(previous, test.current, test.limit); !reflect.DeepEqual(failures, test.expected) {
+			t.Errorf("Incorrect sanity check for %v\n   expected:%v\n   got:     %v", test.current, test.expected, failures)
+		}
+	}
+
+	if failures := sanityCheck(memberCounts{}, memberCounts{}, safetyLimit{Percent: 50}); len(failures) != 0 {
+		t.Errorf("Expected no sanity check failures without previous counts, got %v", failures)
+	}
+}
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/uhppoted/uhppoted-lib/config"
+	"github.com/uhppoted/uhppoted-lib/encoding/conf"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+)
+
+// settings holds the additional 'wild-apricot.*' uhppoted.conf configuration that is specific to
+// uhppoted-app-wild-apricot and not (yet) part of the shared uhppoted-lib configuration.
+type settings struct {
+	WildApricot struct {
+		ACL struct {
+			DefaultValidity string        `conf:"default-validity"`
+			Schedules       string        `conf:"schedules"`
+			Workers         int           `conf:"workers"`
+			MaxCycles       int           `conf:"max-cycles"`
+			Timeout         time.Duration `conf:"timeout"`
+			OnError         string        `conf:"on-error"`
+			MaxDeletions    string        `conf:"max-deletions"`
+			MaxRevocations  string        `conf:"max-revocations"`
+			MaxDrop         string        `conf:"max-drop"`
+			Snapshots       int           `conf:"snapshots"`
+		} `conf:"acl"`
+
+		Rules struct {
+			SHA256    string `conf:"sha256"`
+			PublicKey string `conf:"public-key"`
+			Signature string `conf:"signature"`
+			Headers   string `conf:"headers"`
+		} `conf:"rules"`
+	} `conf:"wild-apricot"`
+
+	Sites Sites `conf:"/^wild-apricot\\.site\\.([^.]+)\\.(controllers|rules)$/"`
+	Zones Zones `conf:"/^wild-apricot\\.zone\\.(.+)$/"`
+
+	validity    acl.Validity
+	schedules   acl.Schedules
+	evaluation  acl.Evaluation
+	zones       acl.Zones
+	controllers acl.Controllers
+	timeout     time.Duration
+	limits      safetyLimits
+	maxDrop     safetyLimit
+	snapshots   int
+	headers     map[string]string
+}
+
+// Parses the 'wild-apricot.*' settings from the configuration file. The shared uhppoted-lib settings
+// (the controllers and wild-apricot.http.client-timeout) are taken from the already loaded
+// configuration, defaulting to the uhppoted-lib defaults if nil.
+func getSettings(file string, cfg *config.Config) (*settings, error) {
+	if cfg == nil {
+		cfg = config.NewConfig()
+	}
+
+	s := settings{}
+
+	if file != "" {
+		bytes, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := conf.Unmarshal(bytes, &s); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, err := acl.ParseValidity(s.WildApricot.ACL.DefaultValidity); err != nil {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.default-validity (%v)", err)
+	} else {
+		s.validity = v
+	}
+
+	// ... a relative schedules file path is relative to the configuration file
+	if path := s.WildApricot.ACL.Schedules; path != "" {
+		if !filepath.IsAbs(path) && file != "" {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+
+		if bytes, err := os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("unable to read schedules file (%v)", err)
+		} else if schedules, err := acl.ParseSchedules(bytes); err != nil {
+			return nil, fmt.Errorf("invalid schedules file %v (%v)", path, err)
+		} else {
+			s.schedules = schedules
+		}
+	}
+
+	if s.WildApricot.ACL.Workers < 0 {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.workers (%v)", s.WildApricot.ACL.Workers)
+	}
+
+	if s.WildApricot.ACL.MaxCycles < 0 {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.max-cycles (%v)", s.WildApricot.ACL.MaxCycles)
+	}
+
+	if s.WildApricot.ACL.Timeout < 0 {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.timeout (%v)", s.WildApricot.ACL.Timeout)
+	}
+
+	if policy, err := acl.ParseFailurePolicy(s.WildApricot.ACL.OnError); err != nil {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.on-error (%v)", err)
+	} else {
+		s.evaluation = acl.Evaluation{
+			MaxCycles: uint64(s.WildApricot.ACL.MaxCycles),
+			Timeout:   s.WildApricot.ACL.Timeout,
+			OnError:   policy,
+		}
+	}
+
+	if limit, err := parseSafetyLimit(s.WildApricot.ACL.MaxDeletions); err != nil {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.max-deletions (%v)", err)
+	} else {
+		s.limits.Deletions = limit
+	}
+
+	if limit, err := parseSafetyLimit(s.WildApricot.ACL.MaxRevocations); err != nil {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.max-revocations (%v)", err)
+	} else {
+		s.limits.Revocations = limit
+	}
+
+	if strings.TrimSpace(s.WildApricot.ACL.MaxDrop) == "" {
+		s.maxDrop = safetyLimit{Percent: 50}
+	} else if limit, err := parseSafetyLimit(s.WildApricot.ACL.MaxDrop); err != nil {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.max-drop (%v)", err)
+	} else {
+		s.maxDrop = limit
+	}
+
+	if s.WildApricot.ACL.Snapshots < 0 {
+		return nil, fmt.Errorf("invalid wild-apricot.acl.snapshots (%v)", s.WildApricot.ACL.Snapshots)
+	} else if s.WildApricot.ACL.Snapshots == 0 {
+		s.snapshots = 30
+	} else {
+		s.snapshots = s.WildApricot.ACL.Snapshots
+	}
+
+	s.zones = acl.Zones(s.Zones)
+	s.controllers = acl.Controllers{}
+
+	for id, device := range cfg.Devices {
+		doors := []string{}
+		for _, d := range device.Doors {
+			if d = clean(d); d != "" {
+				doors = append(doors, d)
+			}
+		}
+
+		s.controllers[id] = doors
+	}
+
+	s.timeout = cfg.WildApricot.HTTP.ClientTimeout
+
+	if headers, err := getHeaders(s.WildApricot.Rules.Headers); err != nil {
+		return nil, err
+	} else {
+		s.headers = headers
+	}
+
+	return &s, nil
+}
+
+// Returns the options for fetching a rules (or signature) file. The custom HTTP headers are only
+// included for a URI on the same host as one of the configured rules URIs (the origins) so that
+// the credentials are not sent to a third party host e.g. for an absolute '@include' URI.
+func (s *settings) fetchOptions(uri string, origins []string, workdir string, cache *httpCache) fetchOptions {
+	options := fetchOptions{
+		Cache:   cache,
+		Workdir: workdir,
+	}
+
+	if s != nil {
+		options.Timeout = s.timeout
+
+		if h := host(uri); h != "" && slices.Contains(origins, h) {
+			options.Headers = s.headers
+		}
+	}
+
+	return options
+}
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppoted-lib/config"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+)
+
+func TestSettings(t *testing.T) {
+	expected := acl.Validity{
+		Policy: acl.Rolling,
+		Days:   30,
+	}
+
+	conf := config.NewConfig()
+	if err := conf.Load("test_uhppoted.conf"); err != nil {
+		t.Fatalf("Unexpected error loading configuration (%v)", err)
+	}
+
+	s, err := getSettings("test_uhppoted.conf", conf)
+	if err != nil {
+		t.Fatalf("Unexpected error reading settings (%v)", err)
+	} else if s == nil {
+		t.Fatalf("Invalid settings (%v)", s)
+	}
+
+	if !reflect.DeepEqual(s.validity, expected) {
+		t.Errorf("Incorrect default validity:\n   expected:%v,\n   got:     %v", expected, s.validity)
+	}
+
+	if len(s.schedules) != 1 || s.schedules[0].Name != "weekday-evenings" || s.schedules[0].Profile.ID != 100 {
+		t.Errorf("Incorrect schedules:\n   expected:%v,\n   got:     %v", "weekday-evenings:100", s.schedules)
+	}
+
+	if s.WildApricot.ACL.Workers != 4 {
+		t.Errorf("Incorrect workers:\n   expected:%v,\n   got:     %v", 4, s.WildApricot.ACL.Workers)
+	}
+
+	evaluation := acl.Evaluation{
+		MaxCycles: 1000,
+		Timeout:   5 * time.Second,
+		OnError:   acl.KeepPrevious,
+	}
+
+	if s.evaluation != evaluation {
+		t.Errorf("Incorrect evaluation:\n   expected:%v,\n   got:     %v", evaluation, s.evaluation)
+	}
+
+	limits := safetyLimits{
+		Deletions:   safetyLimit{Percent: 10},
+		Revocations: safetyLimit{Count: 25},
+	}
+
+	if s.limits != limits {
+		t.Errorf("Incorrect safety limits:\n   expected:%v,\n   got:     %v", limits, s.limits)
+	}
+
+	if s.timeout != 15*time.Second {
+		t.Errorf("Incorrect client timeout:\n   expected:%v,\n   got:     %v", 15*time.Second, s.timeout)
+	}
+
+	controllers := acl.Controllers{
+		405419896: []string{"Great Hall", "Kitchen", "Dungeon", "Hogsmeade"},
+	}
+
+	if !reflect.DeepEqual(s.controllers, controllers) {
+		t.Errorf("Incorrect controllers:\n   expected:%v,\n   got:     %v", controllers, s.controllers)
+	}
+
+	sites := Sites{
+		"hogwarts": &Site{Name: "hogwarts", Controllers: []uint32{405419896}, Rules: "hogwarts.grl"},
+	}
+
+	if !reflect.DeepEqual(s.Sites, sites) {
+		t.Errorf("Incorrect sites:\n   expected:%v,\n   got:     %v", sites, s.Sites)
+	}
+
+	zones := acl.Zones{
+		"Dungeons": []string{"Dungeon", "Kitchen"},
+	}
+
+	if !reflect.DeepEqual(s.zones, zones) {
+		t.Errorf("Incorrect zones:\n   expected:%v,\n   got:     %v", zones, s.zones)
+	}
+}
+
+func TestSettingsWithoutConfigFile(t *testing.T) {
+	expected := acl.Validity{
+		Policy: acl.EndOfYear,
+	}
+
+	s, err := getSettings("", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error reading settings (%v)", err)
+	}
+
+	if !reflect.DeepEqual(s.validity, expected) {
+		t.Errorf("Incorrect default validity:\n   expected:%v,\n   got:     %v", expected, s.validity)
+	}
+
+	if s.timeout != 10*time.Second {
+		t.Errorf("Incorrect default client timeout:\n   expected:%v,\n   got:     %v", 10*time.Second, s.timeout)
+	}
+
+	if s.snapshots != 30 {
+		t.Errorf("Incorrect default snapshot retention:\n   expected:%v,\n   got:     %v", 30, s.snapshots)
+	}
+
+	if maxDrop := (safetyLimit{Percent: 50}); s.maxDrop != maxDrop {
+		t.Errorf("Incorrect default max. drop:\n   expected:%v,\n   got:     %v", maxDrop, s.maxDrop)
+	}
+}
+
+func TestSettingsWithMissingFile(t *testing.T) {
+	if _, err := getSettings("test_missing-file.conf", nil); err == nil {
+		t.Errorf("Expected error reading missing configuration file, got %v", err)
+	}
+}
+
+func TestSettingsWithRelativeSchedules(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "uhppoted.conf")
+
+	schedules, err := os.ReadFile("test_schedules.json")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "schedules.json"), schedules, 0600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := os.WriteFile(file, []byte("wild-apricot.acl.schedules = schedules.json\n"), 0600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	s, err := getSettings(file, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error reading settings (%v)", err)
+	}
+
+	if len(s.schedules) != 1 || s.schedules[0].Name != "weekday-evenings" || s.schedules[0].Profile.ID != 100 {
+		t.Errorf("Incorrect schedules:\n   expected:%v,\n   got:     %v", "weekday-evenings:100", s.schedules)
+	}
+}
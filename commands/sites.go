@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+	"github.com/uhppoted/uhppoted-lib/config"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+)
+
+// Site is a set of controllers with its own rules file, for which load-acl generates and loads
+// a separate ACL. Sites are defined in uhppoted.conf, e.g.:
+//
+//	wild-apricot.site.hogsmeade.controllers = 405419896, 303986753
+//	wild-apricot.site.hogsmeade.rules = https://example.com/rules/hogsmeade.grl
+type Site struct {
+	Name        string
+	Controllers []uint32
+	Rules       string
+}
+
+type Sites map[string]*Site
+
+// site is a configured site resolved against the controllers in the configuration.
+type site struct {
+	Name    string
+	Rules   string
+	Devices []uhppote.Device
+}
+
+func (s *Sites) UnmarshalConf(tag string, values map[string]string) (any, error) {
+	re := regexp.MustCompile(`^/(.*?)/$`)
+	match := re.FindStringSubmatch(tag)
+	if len(match) < 2 {
+		return s, fmt.Errorf("invalid 'conf' regular expression tag: %s", tag)
+	}
+
+	re, err := regexp.Compile(match[1])
+	if err != nil {
+		return s, err
+	}
+
+	if *s == nil {
+		*s = Sites{}
+	}
+
+	for key, value := range values {
+		match := re.FindStringSubmatch(key)
+		if len(match) < 3 {
+			continue
+		}
+
+		name := match[1]
+		v, ok := (*s)[name]
+		if !ok {
+			v = &Site{Name: name}
+			(*s)[name] = v
+		}
+
+		switch match[2] {
+		case "controllers":
+			for _, c := range strings.Split(value, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					if id, err := strconv.ParseUint(c, 10, 32); err != nil || id == 0 {
+						return s, fmt.Errorf("site %v: invalid controller '%v'", name, c)
+					} else {
+						v.Controllers = append(v.Controllers, uint32(id))
+					}
+				}
+			}
+
+		case "rules":
+			v.Rules = strings.TrimSpace(value)
+		}
+	}
+
+	return s, nil
+}
+
+// Groups the configured controllers by site. Controllers that are not assigned to a site are
+// grouped into a default site (with a blank name) that uses the default rules file, which is
+// the only site if no sites are configured. Sites without a rules file also use the default
+// rules file.
+func getSites(sites Sites, rules string, devices []uhppote.Device) ([]site, error) {
+	assigned := map[uint32]string{}
+	list := []site{}
+
+	for _, name := range slices.Sorted(maps.Keys(sites)) {
+		s := sites[name]
+		v := site{
+			Name:  name,
+			Rules: s.Rules,
+		}
+
+		if v.Rules == "" {
+			v.Rules = rules
+		}
+
+		for _, id := range s.Controllers {
+			if other, ok := assigned[id]; ok {
+				return nil, fmt.Errorf("controller %v is assigned to both site %v and site %v", id, other, name)
+			}
+
+			ix := slices.IndexFunc(devices, func(d uhppote.Device) bool { return d.DeviceID == id })
+			if ix < 0 {
+				return nil, fmt.Errorf("site %v: controller %v is not configured", name, id)
+			}
+
+			assigned[id] = name
+			v.Devices = append(v.Devices, devices[ix])
+		}
+
+		list = append(list, v)
+	}
+
+	unassigned := site{
+		Rules: rules,
+	}
+
+	for _, d := range devices {
+		if _, ok := assigned[d.DeviceID]; !ok {
+			unassigned.Devices = append(unassigned.Devices, d)
+		}
+	}
+
+	if len(unassigned.Devices) > 0 || len(list) == 0 {
+		list = append(list, unassigned)
+	}
+
+	return list, nil
+}
+
+func (s site) String() string {
+	if s.Name == "" {
+		return "(default)"
+	}
+
+	return s.Name
+}
+
+// Loads the rules file for a site and returns the rules with the doors for the site controllers.
+// The rules files for named sites are stashed in the sites/<name> subdirectory of the workdir.
+func getSiteRules(conf *config.Config, settings *settings, s site, workdir string, debug bool) (*acl.Rules, Doors, error) {
+	if s.Name != "" {
+		workdir = filepath.Join(workdir, "sites", s.Name)
+		if err := os.MkdirAll(workdir, 0770); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rules, err := getRules(s.Rules, workdir, settings, debug)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load ruleset for site %v (%v)", s, err)
+	}
+
+	doors, err := getSiteDoors(conf, s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rules, doors, nil
+}
+
+// Returns the file path for the output file for a site, i.e. the file path with the site name
+// inserted before the extension for a named site (e.g. acl.hogsmeade.tsv).
+func siteFile(file string, s site) string {
+	if s.Name == "" {
+		return file
+	}
+
+	ext := filepath.Ext(file)
+
+	return strings.TrimSuffix(file, ext) + "." + s.Name + ext
+}
+
+// Returns the doors for the controllers at a site.
+func getSiteDoors(conf *config.Config, s site) (Doors, error) {
+	c := *conf
+	c.Devices = config.DeviceMap{}
+
+	for _, d := range s.Devices {
+		if v, ok := conf.Devices[d.DeviceID]; ok {
+			c.Devices[d.DeviceID] = v
+		}
+	}
+
+	return getDoors(&c)
+}
+
+// Checks for cards that have different start/end dates or PINs in the ACLs for different sites.
+// Cards that have not been granted access to any door at a site are ignored.
+func siteConflicts(sites []site, tables []*lib.Table) []string {
+	type card struct {
+		site  string
+		value string
+	}
+
+	cards := map[string]card{}
+	conflicts := []string{}
+
+	for i, table := range tables {
+		index := map[string]int{}
+		for ix, h := range table.Header {
+			index[h] = ix
+		}
+
+		doors := index["To"] + 1
+
+		for _, record := range table.Records {
+			if !slices.ContainsFunc(record[doors:], func(v string) bool { return v != "N" && v != "" }) {
+				continue
+			}
+
+			cardNumber := record[index["Card Number"]]
+			value := fmt.Sprintf("%v:%v", record[index["From"]], record[index["To"]])
+			if ix, ok := index["PIN"]; ok {
+				value += fmt.Sprintf(" PIN:%v", record[ix])
+			}
+
+			if c, ok := cards[cardNumber]; !ok {
+				cards[cardNumber] = card{site: sites[i].String(), value: value}
+			} else if c.value != value {
+				conflicts = append(conflicts, fmt.Sprintf("card %v: site %v (%v) conflicts with site %v (%v)", cardNumber, sites[i], value, c.site, c.value))
+			}
+		}
+	}
+
+	return conflicts
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+)
+
+func TestGetSites(t *testing.T) {
+	devices := []uhppote.Device{
+		{DeviceID: 201020304},
+		{DeviceID: 303986753},
+		{DeviceID: 405419896},
+	}
+
+	sites := Sites{
+		"hogsmeade": &Site{Name: "hogsmeade", Controllers: []uint32{303986753}, Rules: "hogsmeade.grl"},
+		"hogwarts":  &Site{Name: "hogwarts", Controllers: []uint32{405419896}},
+	}
+
+	expected := []site{
+		{Name: "hogsmeade", Rules: "hogsmeade.grl", Devices: []uhppote.Device{devices[1]}},
+		{Name: "hogwarts", Rules: "default.grl", Devices: []uhppote.Device{devices[2]}},
+		{Name: "", Rules: "default.grl", Devices: []uhppote.Device{devices[0]}},
+	}
+
+	if list, err := getSites(sites, "default.grl", devices); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	} else if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Incorrect sites\n   expected:%+v\n   got:     %+v", expected, list)
+	}
+
+	// ... no sites
+	if list, err := getSites(nil, "default.grl", devices); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	} else if len(list) != 1 || list[0].Rules != "default.grl" || len(list[0].Devices) != 3 {
+		t.Errorf("Incorrect default site\n   expected:%+v\n   got:     %+v", "all controllers", list)
+	}
+
+	// ... invalid sites
+	sites["hogsmeade"].Controllers = []uint32{405419896}
+	if _, err := getSites(sites, "default.grl", devices); err == nil {
+		t.Errorf("Expected error for controller assigned to multiple sites, got %v", err)
+	}
+
+	sites["hogsmeade"].Controllers = []uint32{12345}
+	if _, err := getSites(sites, "default.grl", devices); err == nil {
+		t.Errorf("Expected error for unknown controller, got %v", err)
+	}
+}
+
+func TestSiteConflicts(t *testing.T) {
+	sites := []site{{Name: "hogwarts"}, {Name: "hogsmeade"}}
+	tables := []*lib.Table{
+		{
+			Header: []string{"Card Number", "From", "To", "Great Hall"},
+			Records: [][]string{
+				{"1000001", "2024-01-01", "2024-12-31", "Y"},
+				{"6000001", "2024-01-01", "2024-12-31", "Y"},
+				{"6000002", "2024-01-01", "2024-12-31", "N"},
+			},
+		},
+		{
+			Header: []string{"Card Number", "From", "To", "Three Broomsticks"},
+			Records: [][]string{
+				{"1000001", "2024-01-01", "2024-12-31", "Y"},
+				{"6000001", "2024-01-01", "2024-06-30", "29"},
+				{"6000002", "2024-01-01", "2024-06-30", "Y"},
+			},
+		},
+	}
+
+	expected := []string{
+		"card 6000001: site hogsmeade (2024-01-01:2024-06-30) conflicts with site hogwarts (2024-01-01:2024-12-31)",
+	}
+
+	if conflicts := siteConflicts(sites, tables); !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("Incorrect conflicts\n   expected:%v\n   got:     %v", expected, conflicts)
+	}
+}
+
+func TestSiteFile(t *testing.T) {
+	tests := []struct {
+		file     string
+		site     site
+		expected string
+	}{
+		{"acl.tsv", site{}, "acl.tsv"},
+		{"acl.tsv", site{Name: "hogsmeade"}, "acl.hogsmeade.tsv"},
+		{"reports/acl", site{Name: "hogsmeade"}, "reports/acl.hogsmeade"},
+	}
+
+	for _, test := range tests {
+		if file := siteFile(test.file, test.site); file != test.expected {
+			t.Errorf("Incorrect file for site '%v'\n   expected:%v\n   got:     %v", test.site, test.expected, file)
+		}
+	}
+}
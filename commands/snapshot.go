@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+)
+
+// snapshot is the ACL applied to the controllers by a successful load-acl, with the version
+// information for the members, rules and ACL. Snapshots are stored in the workdir (in
+// .wild-apricot/snapshots/<account ID>/<snapshot ID>.json) and the snapshot ID is the load-acl
+// timestamp e.g. 20260115-093000.
+type snapshot struct {
+	versionInfo
+	ID      string         `json:"id"`
+	WithPIN bool           `json:"with-pin"`
+	Sites   []snapshotSite `json:"sites"`
+}
+
+// snapshotSite is the ACL table loaded onto the controllers for a site.
+type snapshotSite struct {
+	Name        string     `json:"name,omitempty"`
+	Controllers []uint32   `json:"controllers"`
+	Header      []string   `json:"header"`
+	Records     [][]string `json:"records"`
+}
+
+func snapshotDir(workdir string, accountID uint32) string {
+	return filepath.Join(workdir, ".wild-apricot", "snapshots", fmt.Sprintf("%v", accountID))
+}
+
+func newSnapshot(version versionInfo, withPIN bool, sites []site, tables []*lib.Table) snapshot {
+	s := snapshot{
+		versionInfo: version,
+		WithPIN:     withPIN,
+		Sites:       []snapshotSite{},
+	}
+
+	if version.Timestamp != nil {
+		s.ID = version.Timestamp.Format("20060102-150405")
+	} else {
+		s.ID = time.Now().Format("20060102-150405")
+	}
+
+	for i, site := range sites {
+		v := snapshotSite{
+			Name:        site.Name,
+			Controllers: []uint32{},
+			Header:      tables[i].Header,
+			Records:     tables[i].Records,
+		}
+
+		for _, d := range site.Devices {
+			v.Controllers = append(v.Controllers, d.DeviceID)
+		}
+
+		s.Sites = append(s.Sites, v)
+	}
+
+	return s
+}
+
+func storeSnapshot(workdir string, s snapshot) error {
+	bytes, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := snapshotDir(workdir, s.AccountID)
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return err
+	}
+
+	return write(filepath.Join(dir, s.ID+".json"), append(bytes, '\n'))
+}
+
+// Deletes the oldest snapshots for an account, keeping the most recent 'keep' snapshots. Returns
+// the IDs of the deleted snapshots.
+func pruneSnapshots(workdir string, accountID uint32, keep int) ([]string, error) {
+	snapshots, err := listSnapshots(workdir, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	pruned := []string{}
+	if keep > 0 && len(snapshots) > keep {
+		dir := snapshotDir(workdir, accountID)
+		for _, s := range snapshots[:len(snapshots)-keep] {
+			if err := os.Remove(filepath.Join(dir, s.ID+".json")); err != nil {
+				return pruned, err
+			}
+
+			pruned = append(pruned, s.ID)
+		}
+	}
+
+	return pruned, nil
+}
+
+// Returns the snapshots for an account, oldest first.
+func listSnapshots(workdir string, accountID uint32) ([]snapshot, error) {
+	dir := snapshotDir(workdir, accountID)
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []snapshot{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshots := []snapshot{}
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+
+		bytes, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		s := snapshot{}
+		if err := json.Unmarshal(bytes, &s); err != nil {
+			return nil, fmt.Errorf("invalid snapshot %v (%v)", f.Name(), err)
+		} else if s.AccountID != accountID {
+			continue
+		}
+
+		snapshots = append(snapshots, s)
+	}
+
+	slices.SortFunc(snapshots, func(p, q snapshot) int { return strings.Compare(p.ID, q.ID) })
+
+	return snapshots, nil
+}
+
+// Returns the snapshot with the ID, or the snapshot before the latest snapshot (i.e. the ACL
+// loaded before the current ACL) for 'previous'.
+func findSnapshot(snapshots []snapshot, id string) (*snapshot, error) {
+	id = strings.TrimSpace(id)
+
+	if strings.EqualFold(id, "previous") {
+		if len(snapshots) < 2 {
+			return nil, fmt.Errorf("no previous snapshot")
+		}
+
+		return &snapshots[len(snapshots)-2], nil
+	}
+
+	for i := range snapshots {
+		if snapshots[i].ID == id {
+			return &snapshots[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown snapshot '%v'", id)
+}
+
+// Returns the ACL table and configured controllers for a snapshot site.
+func (s snapshotSite) resolve(devices []uhppote.Device) (*lib.Table, []uhppote.Device, error) {
+	list := []uhppote.Device{}
+	for _, id := range s.Controllers {
+		ix := slices.IndexFunc(devices, func(d uhppote.Device) bool { return d.DeviceID == id })
+		if ix < 0 {
+			return nil, nil, fmt.Errorf("controller %v is not configured", id)
+		}
+
+		list = append(list, devices[ix])
+	}
+
+	table := lib.Table{
+		Header:  s.Header,
+		Records: s.Records,
+	}
+
+	return &table, list, nil
+}
+
+func (s snapshot) cards() int {
+	cards := 0
+	for _, site := range s.Sites {
+		cards += len(site.Records)
+	}
+
+	return cards
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/uhppote"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+)
+
+func TestSnapshots(t *testing.T) {
+	workdir := t.TempDir()
+
+	controllers := []uhppote.Device{
+		{DeviceID: 405419896},
+		{DeviceID: 303986753},
+	}
+
+	sites := []site{
+		{Devices: controllers},
+	}
+
+	table := func(cards ...string) []*lib.Table {
+		t := lib.Table{
+			Header:  []string{"Card Number", "From", "To", "Great Hall", "Gryffindor", "Dungeon"},
+			Records: [][]string{},
+		}
+
+		for _, card := range cards {
+			t.Records = append(t.Records, []string{card, "2026-01-01", "2026-12-31", "Y", "Y", "N"})
+		}
+
+		return []*lib.Table{&t}
+	}
+
+	snapshotAt := func(timestamp string, tables []*lib.Table) snapshot {
+		ts, _ := time.ParseInLocation("2006-01-02 15:04:05", timestamp, time.Local)
+		version := versionInfo{
+			AccountID: 12345,
+			Timestamp: &ts,
+		}
+
+		return newSnapshot(version, false, sites, tables)
+	}
+
+	for _, s := range []snapshot{
+		snapshotAt("2026-02-01 09:30:00", table("1000001", "6000001")),
+		snapshotAt("2026-01-15 09:30:00", table("1000001", "6000001", "2000001")),
+		snapshotAt("2026-02-15 09:30:00", table("1000001")),
+	} {
+		if err := storeSnapshot(workdir, s); err != nil {
+			t.Fatalf("Unexpected error (%v)", err)
+		}
+	}
+
+	snapshots, err := listSnapshots(workdir, 12345)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	ids := []string{}
+	for _, s := range snapshots {
+		ids = append(ids, s.ID)
+	}
+
+	if expected := []string{"20260115-093000", "20260201-093000", "20260215-093000"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Incorrect snapshots\n   expected:%v\n   got:     %v", expected, ids)
+	}
+
+	tests := []struct {
+		id       string
+		expected string
+		cards    int
+	}{
+		{"20260115-093000", "20260115-093000", 3},
+		{"previous", "20260201-093000", 2},
+	}
+
+	for _, test := range tests {
+		if s, err := findSnapshot(snapshots, test.id); err != nil {
+			t.Errorf("Unexpected error (%v)", err)
+		} else if s.ID != test.expected || s.cards() != test.cards {
+			t.Errorf("Incorrect snapshot for '%v'\n   expected:%v (%v cards)\n   got:     %v (%v cards)", test.id, test.expected, test.cards, s.ID, s.cards())
+		}
+	}
+
+	if _, err := findSnapshot(snapshots, "20260301-093000"); err == nil {
+		t.Errorf("Expected error for unknown snapshot")
+	}
+
+	if _, err := findSnapshot(snapshots[:1], "previous"); err == nil {
+		t.Errorf("Expected error for missing previous snapshot")
+	}
+
+	if table, devices, err := snapshots[0].Sites[0].resolve(controllers); err != nil {
+		t.Errorf("Unexpected error (%v)", err)
+	} else if !reflect.DeepEqual(devices, controllers) || len(table.Records) != 3 {
+		t.Errorf("Incorrectly resolved snapshot site\n   expected:%v (%v records)\n   got:     %v (%v records)", controllers, 3, devices, len(table.Records))
+	}
+
+	if _, _, err := snapshots[0].Sites[0].resolve(controllers[:1]); err == nil {
+		t.Errorf("Expected error for unconfigured snapshot controller")
+	}
+
+	if snapshots, err := listSnapshots(workdir, 54321); err != nil {
+		t.Errorf("Unexpected error (%v)", err)
+	} else if len(snapshots) != 0 {
+		t.Errorf("Expected no snapshots for unknown account, got %v", snapshots)
+	}
+}
+
+func TestPruneSnapshots(t *testing.T) {
+	workdir := t.TempDir()
+
+	for _, timestamp := range []string{"2026-02-01 09:30:00", "2026-01-15 09:30:00", "2026-02-15 09:30:00"} {
+		ts, _ := time.ParseInLocation("2006-01-02 15:04:05", timestamp, time.Local)
+		version := versionInfo{
+			AccountID: 12345,
+			Timestamp: &ts,
+		}
+
+		if err := storeSnapshot(workdir, newSnapshot(version, false, []site{}, []*lib.Table{})); err != nil {
+			t.Fatalf("Unexpected error (%v)", err)
+		}
+	}
+
+	if pruned, err := pruneSnapshots(workdir, 12345, 2); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	} else if expected := []string{"20260115-093000"}; !reflect.DeepEqual(pruned, expected) {
+		t.Errorf("Incorrect pruned snapshots\n   expected:%v\n   got:     %v", expected, pruned)
+	}
+
+	snapshots, err := listSnapshots(workdir, 12345)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	ids := []string{}
+	for _, s := range snapshots {
+		ids = append(ids, s.ID)
+	}
+
+	if expected := []string{"20260201-093000", "20260215-093000"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Incorrect snapshots after pruning\n   expected:%v\n   got:     %v", expected, ids)
+	}
+
+	if pruned, err := pruneSnapshots(workdir, 12345, 2); err != nil {
+		t.Errorf("Unexpected error (%v)", err)
+	} else if len(pruned) != 0 {
+		t.Errorf("Expected no pruned snapshots, got %v", pruned)
+	}
+}
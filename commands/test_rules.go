@@ -0,0 +1,366 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	lib "github.com/uhppoted/uhppoted-lib/acl"
+	"github.com/uhppoted/uhppoted-lib/config"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/log"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+var TestRulesCmd = TestRules{
+	rules:    filepath.Join(DEFAULT_CONFIG_DIR, "wild-apricot.grl"),
+	members:  "",
+	doors:    "",
+	expected: "",
+	debug:    false,
+}
+
+type TestRules struct {
+	rules    string
+	members  string
+	doors    string
+	expected string
+	debug    bool
+}
+
+func (cmd *TestRules) Name() string {
+	return "test-rules"
+}
+
+func (cmd *TestRules) Description() string {
+	return "Applies the ACL rules to a members list fixture and compares the generated ACL with an expected ACL"
+}
+
+func (cmd *TestRules) Usage() string {
+	return "--rules <file> --members <file> --doors <file> --expected <file>"
+}
+
+func (cmd *TestRules) Help() {
+	fmt.Println()
+	fmt.Printf("  Usage: %s [--debug] [--config <file>] test-rules [--rules <file>] --members <file> [--doors <file>] [--expected <file>]\n", APP)
+	fmt.Println()
+	fmt.Println("  Applies the ACL rules to a members list fixture (TSV or JSON) and compares the generated access control")
+	fmt.Println("  list with the expected access control list, failing with an error if the access control lists differ.")
+	fmt.Println("  The generated ACL is displayed on the console if an expected ACL file is not provided.")
+	fmt.Println()
+
+	helpOptions(cmd.FlagSet())
+
+	fmt.Println()
+	fmt.Println("  Examples:")
+	fmt.Println(`    uhppote-app-wild-apricot test-rules --rules "wild-apricot.grl" \`)
+	fmt.Println(`                                        --members "test/members.tsv" \`)
+	fmt.Println(`                                        --doors "test/doors.txt" \`)
+	fmt.Println(`                                        --expected "test/ACL.tsv"`)
+	fmt.Println()
+}
+
+func (cmd *TestRules) FlagSet() *flag.FlagSet {
+	flagset := flag.NewFlagSet("test-rules", flag.ExitOnError)
+
+	flagset.StringVar(&cmd.rules, "rules", cmd.rules, "URI for the 'grule' rules file (.grl) policy file (.yaml or .json) or CEL rules file (.cel). Supports comma separated lists of URIs and '@include' directives. Defaults to "+cmd.rules)
+	flagset.StringVar(&cmd.members, "members", cmd.members, "File path for the members list fixture, in the 'get-members' TSV format or as JSON (.json extension)")
+	flagset.StringVar(&cmd.doors, "doors", cmd.doors, "File path for the door list (one door per line). Defaults to the doors in the uhppoted.conf file")
+	flagset.StringVar(&cmd.expected, "expected", cmd.expected, "File path for the expected ACL, in the 'get-acl' TSV format. Displays the generated ACL if not provided")
+
+	return flagset
+}
+
+func (cmd *TestRules) Execute(args ...any) error {
+	options := args[0].(*Options)
+
+	cmd.debug = options.Debug
+
+	log.SetDebug(options.Debug)
+
+	// ... check parameters
+	if strings.TrimSpace(cmd.rules) == "" {
+		return fmt.Errorf("invalid rules file")
+	}
+
+	if strings.TrimSpace(cmd.members) == "" {
+		return fmt.Errorf("invalid members file")
+	}
+
+	// ... get settings, rules, members and doors
+	settings := &settings{}
+	if _, err := os.Stat(options.Config); err == nil {
+		conf := config.NewConfig()
+		if err := conf.Load(options.Config); err != nil {
+			return fmt.Errorf("could not load configuration (%v)", err)
+		}
+
+		if settings, err = getSettings(options.Config, conf); err != nil {
+			return fmt.Errorf("could not load configuration (%v)", err)
+		}
+	} else if cmd.doors == "" {
+		return fmt.Errorf("could not load configuration (%v)", err)
+	}
+
+	rules, err := cmd.getRules(settings)
+	if err != nil {
+		return err
+	}
+
+	members, err := getMembersFixture(cmd.members)
+	if err != nil {
+		return err
+	}
+
+	doors, err := cmd.getDoors(options.Config)
+	if err != nil {
+		return err
+	}
+
+	// ... generate ACL
+	if cmd.expected == "" {
+		ACL, err := rules.MakeACL(*members, doors)
+		if err != nil {
+			return err
+		}
+
+		logFailures(ACL, settings.evaluation.OnError)
+
+		fmt.Fprintln(os.Stdout, string(ACL.AsTable().MarshalTextIndent("  ", " ")))
+		return nil
+	}
+
+	expected, err := getExpectedACL(cmd.expected)
+	if err != nil {
+		return err
+	}
+
+	columns := acl.Columns{
+		PIN:      slices.Contains(expected.Header, "PIN"),
+		Name:     slices.Contains(expected.Header, "Name"),
+		MemberID: slices.Contains(expected.Header, "Member ID"),
+	}
+
+	makeACL := func(members types.Members, doors []string) (*acl.ACL, error) {
+		if columns.PIN {
+			return rules.MakeACLWithPIN(members, doors)
+		} else {
+			return rules.MakeACL(members, doors)
+		}
+	}
+
+	ACL, err := makeACL(*members, doors)
+	if err != nil {
+		return err
+	}
+
+	logFailures(ACL, settings.evaluation.OnError)
+
+	if cmd.debug {
+		fmt.Printf("ACL:\n%s\n", string(ACL.AsTableWith(columns).MarshalTextIndent("  ", " ")))
+	}
+
+	// ... compare
+	differences := diffTables(ACL.AsTableWith(columns), expected)
+	if len(differences) > 0 {
+		for _, d := range differences {
+			fmt.Printf("  %v\n", d)
+		}
+		fmt.Println()
+
+		return fmt.Errorf("generated ACL does not match expected ACL %v (%v differences)", cmd.expected, len(differences))
+	}
+
+	infof("Generated ACL matches expected ACL %v", cmd.expected)
+
+	return nil
+}
+
+// Loads the rules (including merged and included rules files) the same way as load-acl, but with
+// a temporary working directory so that the test rules are not stashed over the load-acl rules.
+func (cmd *TestRules) getRules(settings *settings) (*acl.Rules, error) {
+	workdir, err := os.MkdirTemp("", "wild-apricot-test-rules-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(workdir)
+
+	return getRules(cmd.rules, workdir, settings, cmd.debug)
+}
+
+func (cmd *TestRules) getDoors(file string) ([]string, error) {
+	if cmd.doors == "" {
+		conf := config.NewConfig()
+		if err := conf.Load(file); err != nil {
+			return nil, fmt.Errorf("could not load configuration (%v)", err)
+		}
+
+		return getDoors(conf)
+	}
+
+	return readDoors(cmd.doors)
+}
+
+// Reads a door list file with one door per line, ignoring blank lines, comments and the
+// 'Door' header and 'Zones' column from get-doors.
+func readDoors(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	doors := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		door, _, _ := strings.Cut(scanner.Text(), "\t")
+		door = clean(door)
+		if door == "" || strings.HasPrefix(door, "#") || (len(doors) == 0 && door == "Door") {
+			continue
+		}
+
+		if slices.ContainsFunc(doors, func(d string) bool { return normalise(d) == normalise(door) }) {
+			return nil, fmt.Errorf("duplicate door '%v' in %v", door, file)
+		}
+
+		doors = append(doors, door)
+	}
+
+	return doors, scanner.Err()
+}
+
+func getMembersFixture(file string) (*types.Members, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var members *types.Members
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		members, err = types.ParseMembersJSON(b)
+	} else {
+		members, err = types.ParseMembersTSV(bytes.NewReader(b))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid members file %v (%v)", file, err)
+	}
+
+	return members, nil
+}
+
+func getExpectedACL(file string) (*lib.Table, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comma = '\t'
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expected ACL file %v (%v)", file, err)
+	} else if len(rows) < 1 {
+		return nil, fmt.Errorf("invalid expected ACL file %v (missing header)", file)
+	}
+
+	table := lib.Table{
+		Header:  []string{},
+		Records: rows[1:],
+	}
+
+	for _, h := range rows[0] {
+		table.Header = append(table.Header, clean(h))
+	}
+
+	if !slices.Contains(table.Header, "Card Number") {
+		return nil, fmt.Errorf("invalid expected ACL file %v (missing 'Card Number' column)", file)
+	}
+
+	return &table, nil
+}
+
+// Compares a generated ACL with an expected ACL, matching records by card number and columns by
+// name. The 'From' and 'To' columns are only compared if they are included in the expected ACL
+// because the default start and end dates change from year to year.
+func diffTables(generated, expected *lib.Table) []string {
+	differences := []string{}
+
+	index := func(t *lib.Table) (map[string]int, map[string][]string) {
+		columns := map[string]int{}
+		records := map[string][]string{}
+
+		for i, h := range t.Header {
+			columns[normalise(h)] = i
+		}
+
+		for _, r := range t.Records {
+			if ix := columns["cardnumber"]; ix < len(r) {
+				records[strings.TrimSpace(r[ix])] = r
+			}
+		}
+
+		return columns, records
+	}
+
+	field := func(r []string, ix int) string {
+		if ix < len(r) {
+			return strings.TrimSpace(r[ix])
+		}
+
+		return ""
+	}
+
+	p, generatedRecords := index(generated)
+	q, expectedRecords := index(expected)
+
+	for _, h := range generated.Header {
+		if _, ok := q[normalise(h)]; !ok && h != "From" && h != "To" {
+			differences = append(differences, fmt.Sprintf("expected ACL is missing column '%v'", h))
+		}
+	}
+
+	for _, h := range expected.Header {
+		if _, ok := p[normalise(h)]; !ok {
+			differences = append(differences, fmt.Sprintf("expected ACL has unknown column '%v'", h))
+		}
+	}
+
+	for _, r := range generated.Records {
+		card := field(r, p["cardnumber"])
+		if _, ok := expectedRecords[card]; !ok {
+			differences = append(differences, fmt.Sprintf("card %v: not in expected ACL", card))
+		}
+	}
+
+	for _, r := range expected.Records {
+		card := field(r, q["cardnumber"])
+		g, ok := generatedRecords[card]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("card %v: not in generated ACL", card))
+			continue
+		}
+
+		for _, h := range expected.Header {
+			if ix, ok := p[normalise(h)]; ok {
+				if u, v := field(g, ix), field(r, q[normalise(h)]); u != v {
+					differences = append(differences, fmt.Sprintf("card %v: %v - expected:%v, got:%v", card, h, v, u))
+				}
+			}
+		}
+	}
+
+	return differences
+}
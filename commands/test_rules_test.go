@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTestRules(t *testing.T) {
+	for _, members := range []string{"test_members.tsv", "test_members.json"} {
+		cmd := TestRules{
+			rules:    "test_rules.grl",
+			members:  members,
+			doors:    "test_doors.txt",
+			expected: "test_acl.tsv",
+		}
+
+		if err := cmd.Execute(&Options{}); err != nil {
+			t.Errorf("Unexpected error testing rules with %v (%v)", members, err)
+		}
+	}
+}
+
+func TestTestRulesWithIncludes(t *testing.T) {
+	workdir := t.TempDir()
+
+	bytes, err := os.ReadFile("test_rules.grl")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	// ... split test_rules.grl into an including site rules file and an included common rules file
+	ix := strings.Index(string(bytes), "rule Suspended")
+	common := string(bytes[:ix]) + "// *** END GRULES ***\n"
+	site := "// *** GRULES ***\n// @include common.grl\n" + string(bytes[ix:])
+
+	files := map[string]string{
+		"common.grl": common,
+		"site.grl":   site,
+		"empty.grl":  "// *** GRULES ***\n// *** END GRULES ***\n",
+	}
+
+	for k, v := range files {
+		if err := os.WriteFile(filepath.Join(workdir, k), []byte(v), 0600); err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+
+	cmd := TestRules{
+		rules:    filepath.Join(workdir, "site.grl") + "," + filepath.Join(workdir, "empty.grl"),
+		members:  "test_members.json",
+		doors:    "test_doors.txt",
+		expected: "test_acl.tsv",
+	}
+
+	if err := cmd.Execute(&Options{}); err != nil {
+		t.Errorf("Unexpected error testing rules with @include (%v)", err)
+	}
+}
+
+func TestDiffTables(t *testing.T) {
+	expected := []string{
+		"card 6000002: not in expected ACL",
+		"card 6000001: Dungeon - expected:Y, got:N",
+		"card 6000003: not in generated ACL",
+	}
+
+	cmd := TestRules{
+		rules:   "test_rules.grl",
+		members: "test_members.tsv",
+		doors:   "test_doors.txt",
+	}
+
+	rules, err := cmd.getRules(&settings{})
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	members, err := getMembersFixture(cmd.members)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	doors, err := cmd.getDoors("")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	ACL, err := rules.MakeACL(*members, doors)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	table, err := getExpectedACL("test_acl.tsv")
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	table.Records[2][3] = "Y"
+	table.Records[3][0] = "6000003"
+
+	differences := diffTables(ACL.AsTable(), table)
+
+	if !reflect.DeepEqual(differences, expected) {
+		t.Errorf("Incorrect differences\n   expected:%v\n   got:     %v", expected, differences)
+	}
+}
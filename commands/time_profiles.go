@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+
+	core "github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
+)
+
+// Creates or updates the time profiles for the named schedules on each of the controllers. Profiles
+// that are already defined and match the schedule are left unchanged.
+func putTimeProfiles(u uhppote.IUHPPOTE, devices []uhppote.Device, schedules acl.Schedules, dryrun bool) error {
+	errors := []error{}
+
+	for _, device := range devices {
+		deviceID := device.DeviceID
+
+		for _, schedule := range schedules {
+			profile := schedule.Profile
+
+			current, err := u.GetTimeProfile(deviceID, profile.ID)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("%v  error retrieving time profile %v (%v)", deviceID, profile.ID, err))
+				continue
+			}
+
+			if current != nil && sameProfile(*current, profile) {
+				debugf("%v  time profile %v (%v) unchanged", deviceID, profile.ID, schedule.Name)
+				continue
+			}
+
+			action := "updated"
+			if current == nil {
+				action = "created"
+			}
+
+			if dryrun {
+				infof("%v  time profile %v (%v) %v (dry run)", deviceID, profile.ID, schedule.Name, action)
+				continue
+			}
+
+			if ok, err := u.SetTimeProfile(deviceID, profile); err != nil {
+				errors = append(errors, fmt.Errorf("%v  error setting time profile %v (%v)", deviceID, profile.ID, err))
+			} else if !ok {
+				errors = append(errors, fmt.Errorf("%v  failed to set time profile %v", deviceID, profile.ID))
+			} else {
+				infof("%v  time profile %v (%v) %v", deviceID, profile.ID, schedule.Name, action)
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%v", errors)
+	}
+
+	return nil
+}
+
+// Checks that the time profiles referenced by the ACL are defined on the controllers that manage the
+// doors. Logs the missing, mismatched and expired profiles and returns an error if strict is set.
+func checkTimeProfiles(u uhppote.IUHPPOTE, devices []uhppote.Device, profiles map[string][]uint8, schedules acl.Schedules, clock types.Clock, strict, dryrun bool) error {
+	problems, err := verifyTimeProfiles(u, devices, profiles, schedules, clock, dryrun)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range problems {
+		warnf("%v", p)
+	}
+
+	if strict && len(problems) > 0 {
+		return fmt.Errorf("ACL references %v missing or mismatched time profiles", len(problems))
+	}
+
+	return nil
+}
+
+// Returns a list of the time profiles referenced by the ACL that are either not defined on a
+// controller, do not match the schedule definition or have expired (as of the clock date). Schedule
+// time profiles are not checked for a dry run because they would have been created or updated by
+// putTimeProfiles.
+func verifyTimeProfiles(u uhppote.IUHPPOTE, devices []uhppote.Device, profiles map[string][]uint8, schedules acl.Schedules, clock types.Clock, dryrun bool) ([]error, error) {
+	problems := []error{}
+	today := clock.Today()
+
+	for _, device := range devices {
+		deviceID := device.DeviceID
+		referenced := []uint8{}
+
+		for door, list := range profiles {
+			for _, d := range device.Doors {
+				if normalise(d) != "" && normalise(d) == normalise(door) {
+					referenced = append(referenced, list...)
+				}
+			}
+		}
+
+		slices.Sort(referenced)
+
+		for _, id := range slices.Compact(referenced) {
+			var schedule *acl.Schedule
+			for _, s := range schedules {
+				if s.Profile.ID == id {
+					schedule = &s
+				}
+			}
+
+			if schedule != nil && dryrun {
+				continue
+			}
+
+			profile, err := u.GetTimeProfile(deviceID, id)
+			if err != nil {
+				return nil, fmt.Errorf("%v  error retrieving time profile %v (%v)", deviceID, id, err)
+			}
+
+			switch {
+			case profile == nil:
+				problems = append(problems, fmt.Errorf("%v  time profile %v is not defined", deviceID, id))
+
+			case schedule != nil && !sameProfile(*profile, schedule.Profile):
+				problems = append(problems, fmt.Errorf("%v  time profile %v does not match schedule '%v'", deviceID, id, schedule.Name))
+
+			case !profile.To.IsZero() && profile.To.Before(today):
+				problems = append(problems, fmt.Errorf("%v  time profile %v expired on %v", deviceID, id, profile.To))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+func sameProfile(p, q core.TimeProfile) bool {
+	return p.ID == q.ID &&
+		p.LinkedProfileID == q.LinkedProfileID &&
+		p.From.Equals(q.From) &&
+		p.To.Equals(q.To) &&
+		p.Weekdays.String() == q.Weekdays.String() &&
+		p.Segments.String() == q.Segments.String()
+}
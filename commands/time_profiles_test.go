@@ -0,0 +1,202 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
+
+	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
+)
+
+type stub struct {
+	uhppote.IUHPPOTE
+	profiles map[uint32]map[uint8]types.TimeProfile
+	updated  []uint8
+}
+
+func (s *stub) GetTimeProfile(deviceID uint32, profileID uint8) (*types.TimeProfile, error) {
+	if p, ok := s.profiles[deviceID][profileID]; ok {
+		return &p, nil
+	}
+
+	return nil, nil
+}
+
+func (s *stub) SetTimeProfile(deviceID uint32, profile types.TimeProfile) (bool, error) {
+	if _, ok := s.profiles[deviceID]; !ok {
+		s.profiles[deviceID] = map[uint8]types.TimeProfile{}
+	}
+
+	s.profiles[deviceID][profile.ID] = profile
+	s.updated = append(s.updated, profile.ID)
+
+	return true, nil
+}
+
+func TestPutTimeProfiles(t *testing.T) {
+	evenings := types.TimeProfile{
+		ID:       100,
+		From:     types.ToDate(2026, time.January, 1),
+		To:       types.ToDate(2026, time.December, 31),
+		Weekdays: types.Weekdays{time.Monday: true, time.Friday: true},
+		Segments: types.Segments{
+			1: types.Segment{Start: types.NewHHmm(17, 0), End: types.NewHHmm(21, 0)},
+			2: types.Segment{},
+			3: types.Segment{},
+		},
+	}
+
+	mealtimes := types.TimeProfile{
+		ID:       29,
+		From:     types.ToDate(2026, time.January, 1),
+		To:       types.ToDate(2026, time.December, 31),
+		Weekdays: types.Weekdays{time.Saturday: true, time.Sunday: true},
+		Segments: types.Segments{
+			1: types.Segment{Start: types.NewHHmm(12, 0), End: types.NewHHmm(13, 30)},
+			2: types.Segment{},
+			3: types.Segment{},
+		},
+	}
+
+	schedules := acl.Schedules{
+		acl.Schedule{Name: "mealtimes", Profile: mealtimes},
+		acl.Schedule{Name: "weekday-evenings", Profile: evenings},
+	}
+
+	devices := []uhppote.Device{
+		uhppote.Device{DeviceID: 405419896},
+	}
+
+	u := stub{
+		profiles: map[uint32]map[uint8]types.TimeProfile{
+			405419896: map[uint8]types.TimeProfile{
+				29: mealtimes,
+			},
+		},
+	}
+
+	if err := putTimeProfiles(&u, devices, schedules, false); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(u.updated) != 1 || u.updated[0] != 100 {
+		t.Errorf("Incorrect time profiles updated - expected:%v, got:%v", []uint8{100}, u.updated)
+	}
+
+	if p, ok := u.profiles[405419896][100]; !ok || !sameProfile(p, evenings) {
+		t.Errorf("Incorrect time profile - expected:%v, got:%v", evenings, p)
+	}
+}
+
+func TestPutTimeProfilesWithDryRun(t *testing.T) {
+	schedules := acl.Schedules{
+		acl.Schedule{Name: "weekday-evenings", Profile: types.TimeProfile{ID: 100}},
+	}
+
+	devices := []uhppote.Device{
+		uhppote.Device{DeviceID: 405419896},
+	}
+
+	u := stub{
+		profiles: map[uint32]map[uint8]types.TimeProfile{},
+	}
+
+	if err := putTimeProfiles(&u, devices, schedules, true); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(u.updated) != 0 {
+		t.Errorf("Unexpected time profile update with --dry-run (%v)", u.updated)
+	}
+}
+
+func TestVerifyTimeProfiles(t *testing.T) {
+	profile := func(id uint8, from, to types.Date, start types.HHmm) types.TimeProfile {
+		return types.TimeProfile{
+			ID:       id,
+			From:     from,
+			To:       to,
+			Weekdays: types.Weekdays{time.Monday: true},
+			Segments: types.Segments{
+				1: types.Segment{Start: start, End: types.NewHHmm(21, 0)},
+				2: types.Segment{},
+				3: types.Segment{},
+			},
+		}
+	}
+
+	start := types.ToDate(2020, time.January, 1)
+	end := types.ToDate(2099, time.December, 31)
+	expired := types.ToDate(2021, time.December, 31)
+
+	schedules := acl.Schedules{
+		acl.Schedule{Name: "weekday-evenings", Profile: profile(100, start, end, types.NewHHmm(17, 0))},
+	}
+
+	devices := []uhppote.Device{
+		uhppote.Device{DeviceID: 405419896, Doors: []string{"Great Hall", "Kitchen", "Dungeon", "Hogsmeade"}},
+		uhppote.Device{DeviceID: 303986753, Doors: []string{"Gryffindor", "Hufflepuff", "Ravenclaw", "Slytherin"}},
+	}
+
+	profiles := map[string][]uint8{
+		"Great Hall": []uint8{29, 100},
+		"Kitchen":    []uint8{30},
+		"Gryffindor": []uint8{31},
+	}
+
+	u := stub{
+		profiles: map[uint32]map[uint8]types.TimeProfile{
+			405419896: map[uint8]types.TimeProfile{
+				29:  profile(29, start, end, types.NewHHmm(8, 0)),
+				30:  profile(30, start, expired, types.NewHHmm(8, 0)),
+				100: profile(100, start, end, types.NewHHmm(18, 0)),
+			},
+			303986753: map[uint8]types.TimeProfile{},
+		},
+	}
+
+	expected := []string{
+		"405419896  time profile 30 expired on 2021-12-31",
+		"405419896  time profile 100 does not match schedule 'weekday-evenings'",
+		"303986753  time profile 31 is not defined",
+	}
+
+	problems, err := verifyTimeProfiles(&u, devices, profiles, schedules, nil, false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("Incorrect time profile problems - expected:%v, got:%v", expected, problems)
+	}
+
+	for i := range expected {
+		if problems[i].Error() != expected[i] {
+			t.Errorf("Incorrect time profile problem %v - expected:%v, got:%v", i+1, expected[i], problems[i])
+		}
+	}
+
+	if err := checkTimeProfiles(&u, devices, profiles, schedules, nil, true, false); err == nil {
+		t.Errorf("Expected error for strict time profile check, got %v", err)
+	}
+
+	if err := checkTimeProfiles(&u, devices, profiles, schedules, nil, false, false); err != nil {
+		t.Errorf("Unexpected error for non-strict time profile check (%v)", err)
+	}
+
+	// ... as of a date before time profile 30 expired
+	asOf := func() time.Time {
+		return time.Date(2021, time.June, 30, 0, 0, 0, 0, time.Local)
+	}
+
+	problems, err = verifyTimeProfiles(&u, devices, profiles, schedules, asOf, false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if len(problems) != len(expected)-1 || problems[0].Error() != expected[1] {
+		t.Errorf("Incorrect time profile problems as of 2021-06-30 - expected:%v, got:%v", expected[1:], problems)
+	}
+}
@@ -0,0 +1,160 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// verifyRules checks the downloaded rules file against the configured SHA-256 checksum and/or
+// the detached minisign (or raw ed25519) signature. The signature is fetched from the configured
+// signature URI, defaulting to the rules URI with a .minisig extension.
+func verifyRules(ruleset []byte, uri string, origins []string, workdir string, s *settings) error {
+	if s == nil {
+		return nil
+	}
+
+	if checksum := strings.TrimSpace(s.WildApricot.Rules.SHA256); checksum != "" {
+		hash := sha256.Sum256(ruleset)
+		if !strings.EqualFold(checksum, hex.EncodeToString(hash[:])) {
+			return fmt.Errorf("SHA-256 checksum %v does not match expected checksum %v", hex.EncodeToString(hash[:]), checksum)
+		}
+	}
+
+	if key := strings.TrimSpace(s.WildApricot.Rules.PublicKey); key != "" {
+		signatureURI := strings.TrimSpace(s.WildApricot.Rules.Signature)
+		if signatureURI == "" {
+			signatureURI = uri + ".minisig"
+			if path, query, ok := strings.Cut(uri, "?"); ok && isGitURI(uri) {
+				signatureURI = path + ".minisig?" + query
+			}
+		}
+
+		signature, err := fetch(signatureURI, s.fetchOptions(signatureURI, origins, workdir, nil))
+		if err != nil {
+			return fmt.Errorf("error retrieving signature %v (%v)", signatureURI, err)
+		}
+
+		if err := verifySignature(ruleset, key, signature); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifySignature verifies a minisign signature file (legacy or pre-hashed) or a raw base64
+// encoded ed25519 signature. The public key is either a minisign public key, a raw base64
+// encoded ed25519 public key or the path to a minisign public key file.
+func verifySignature(message []byte, key string, signature []byte) error {
+	pubkey, keyID, err := parsePublicKey(key)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(signature))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		return fmt.Errorf("invalid signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return fmt.Errorf("invalid signature (%v)", err)
+	}
+
+	switch {
+	// ... raw ed25519 signature
+	case len(sig) == ed25519.SignatureSize:
+		if !ed25519.Verify(pubkey, message, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+
+		return nil
+
+	// ... minisign signature
+	case len(sig) == 2+8+ed25519.SignatureSize:
+		algorithm := string(sig[0:2])
+		if keyID != nil && !bytes.Equal(sig[2:10], keyID) {
+			return fmt.Errorf("signature key ID %X does not match public key ID %X", sig[2:10], keyID)
+		}
+
+		switch algorithm {
+		case "Ed":
+			if !ed25519.Verify(pubkey, message, sig[10:]) {
+				return fmt.Errorf("invalid signature")
+			}
+
+		case "ED":
+			hash := blake2b.Sum512(message)
+			if !ed25519.Verify(pubkey, hash[:], sig[10:]) {
+				return fmt.Errorf("invalid signature")
+			}
+
+		default:
+			return fmt.Errorf("unsupported signature algorithm '%v'", algorithm)
+		}
+
+		// ... verify trusted comment
+		if len(lines) > 2 && strings.HasPrefix(lines[1], "trusted comment:") {
+			comment := strings.TrimPrefix(lines[1], "trusted comment: ")
+			global, err := base64.StdEncoding.DecodeString(lines[2])
+			if err != nil || !ed25519.Verify(pubkey, slices.Concat(sig[10:], []byte(comment)), global) {
+				return fmt.Errorf("invalid signature trusted comment")
+			}
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("invalid signature")
+	}
+}
+
+func parsePublicKey(key string) (ed25519.PublicKey, []byte, error) {
+	if b, err := os.ReadFile(key); err == nil {
+		key = ""
+		scanner := bufio.NewScanner(bytes.NewReader(b))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "untrusted comment:") {
+				key = line
+				break
+			}
+		}
+	}
+
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid public key (%v)", err)
+	}
+
+	switch len(b) {
+	case ed25519.PublicKeySize:
+		return ed25519.PublicKey(b), nil, nil
+
+	case 2 + 8 + ed25519.PublicKeySize:
+		if string(b[0:2]) != "Ed" {
+			return nil, nil, fmt.Errorf("unsupported public key algorithm '%v'", string(b[0:2]))
+		}
+
+		return ed25519.PublicKey(b[10:]), b[2:10], nil
+
+	default:
+		return nil, nil, fmt.Errorf("invalid public key")
+	}
+}
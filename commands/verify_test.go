@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestVerifySignature(t *testing.T) {
+	message := []byte("// *** GRULES ***\n// *** END GRULES ***\n")
+	public, private, _ := ed25519.GenerateKey(nil)
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	key := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), public...))
+
+	minisig := func(algorithm string, message []byte) []byte {
+		m := message
+		if algorithm == "ED" {
+			hash := blake2b.Sum512(message)
+			m = hash[:]
+		}
+
+		sig := append(append([]byte(algorithm), keyID...), ed25519.Sign(private, m)...)
+		comment := "timestamp:1760832000"
+		global := ed25519.Sign(private, slices.Concat(sig[10:], []byte(comment)))
+
+		return fmt.Appendf(nil, "untrusted comment: signature\n%v\ntrusted comment: %v\n%v\n",
+			base64.StdEncoding.EncodeToString(sig),
+			comment,
+			base64.StdEncoding.EncodeToString(global))
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		signature []byte
+		valid     bool
+	}{
+		{"minisign", key, minisig("Ed", message), true},
+		{"minisign (prehashed)", key, minisig("ED", message), true},
+		{"raw ed25519", base64.StdEncoding.EncodeToString(public), []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(private, message))), true},
+		{"tampered", key, minisig("Ed", append([]byte("// tampered\n"), message...)), false},
+		{"tampered (prehashed)", key, minisig("ED", append([]byte("// tampered\n"), message...)), false},
+		{"garbage", key, []byte("<html>404 Not Found</html>"), false},
+	}
+
+	for _, test := range tests {
+		err := verifySignature(message, test.key, test.signature)
+		if test.valid && err != nil {
+			t.Errorf("%v: unexpected error (%v)", test.name, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%v: expected error, got %v", test.name, err)
+		}
+	}
+}
+
+func TestLoadRulesWithInvalidChecksum(t *testing.T) {
+	workdir := t.TempDir()
+	stashed := []byte("// *** GRULES ***\n// *** END GRULES ***\n")
+	downloaded := []byte("// *** GRULES ***\nrule Everyone \"\" { when true then permissions.Grant(\"*\"); Retract(\"Everyone\"); }\n// *** END GRULES ***\n")
+	uri := filepath.Join(workdir, "downloaded.grl")
+
+	if err := os.WriteFile(filepath.Join(workdir, "wild-apricot.grl"), stashed, 0600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if err := os.WriteFile(uri, downloaded, 0600); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	s := settings{}
+	s.WildApricot.Rules.SHA256 = "1234"
+
+	rules, err := loadRules(uri, workdir, &s, false)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	hash := sha256.Sum256(stashed)
+	if rules.Hash() != hex.EncodeToString(hash[:]) {
+		t.Errorf("Expected stashed rules, got %v", rules.Hash())
+	}
+
+	// ... valid checksum
+	hash = sha256.Sum256(downloaded)
+	s.WildApricot.Rules.SHA256 = hex.EncodeToString(hash[:])
+
+	if rules, err := loadRules(uri, workdir, &s, false); err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	} else if rules.Hash() != hex.EncodeToString(hash[:]) {
+		t.Errorf("Expected downloaded rules, got %v", rules.Hash())
+	}
+}
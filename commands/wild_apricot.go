@@ -1,15 +1,21 @@
 package commands
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
+	core "github.com/uhppoted/uhppote-core/types"
+	"github.com/uhppoted/uhppote-core/uhppote"
 	"github.com/uhppoted/uhppoted-app-wild-apricot/acl"
 	"github.com/uhppoted/uhppoted-app-wild-apricot/types"
 	"github.com/uhppoted/uhppoted-app-wild-apricot/wild-apricot"
+	lib "github.com/uhppoted/uhppoted-lib/acl"
 	"github.com/uhppoted/uhppoted-lib/config"
 )
 
@@ -126,14 +132,180 @@ func getGroups(conf *config.Config, credentials *credentials) (*types.Groups, er
 }
 
 // Ref. https://github.com/uhppoted/uhppoted-app-wild-apricot/issues/2
-func getRules(uri string, workdir string, dbg bool) (*acl.Rules, error) {
-	ruleset, err := fetch(uri)
+func getRules(uri string, workdir string, settings *settings, dbg bool) (*acl.Rules, error) {
+	rules, err := loadRules(uri, workdir, settings, dbg)
 	if err != nil {
 		return nil, err
 	}
 
-	if dbg {
-		filename := time.Now().Format("RULES 2006-01-02 15:04:05.grl")
+	if settings != nil {
+		rules.SetDefaultValidity(settings.validity)
+		rules.SetSchedules(settings.schedules)
+		rules.SetZones(settings.zones)
+		rules.SetControllers(settings.controllers)
+		rules.SetWorkers(settings.WildApricot.ACL.Workers)
+		rules.SetEvaluation(settings.evaluation)
+	}
+
+	return rules, nil
+}
+
+// Returns the clock for an --as-of date, i.e. a clock fixed at the start of the date or the
+// system clock if the date is blank.
+func getClock(asOf string) (types.Clock, error) {
+	if strings.TrimSpace(asOf) == "" {
+		return nil, nil
+	}
+
+	date, err := core.ParseDate(strings.TrimSpace(asOf))
+	if err != nil || date.IsZero() {
+		return nil, fmt.Errorf("invalid --as-of date '%v'", asOf)
+	}
+
+	return types.AsOf(date), nil
+}
+
+// Retrieves the current ACL from the controllers for the 'keep-previous' rules evaluation
+// failure policy. Falls back to 'deny-all' (with a warning) if the controller ACL could
+// not be retrieved.
+func getPreviousACL(rules *acl.Rules, settings *settings, u uhppote.IUHPPOTE, devices []uhppote.Device) {
+	if settings == nil || settings.evaluation.OnError != acl.KeepPrevious {
+		return
+	}
+
+	current, errors := lib.GetACL(u, devices)
+	if len(errors) > 0 {
+		warnf("Unable to retrieve previous ACL from controllers (%v)", errors)
+		return
+	}
+
+	if table, err := lib.MakeTable(current, devices); err != nil {
+		warnf("Unable to retrieve previous ACL from controllers (%v)", err)
+	} else if err := rules.SetPrevious(table); err != nil {
+		warnf("Unable to retrieve previous ACL from controllers (%v)", err)
+	}
+}
+
+// Logs the members for which the rules could not be evaluated.
+func logFailures(ACL *acl.ACL, policy acl.FailurePolicy) {
+	for _, f := range ACL.Failures() {
+		warnf("Rules evaluation failed for %v - applied '%v' policy", f, policy)
+	}
+}
+
+// Loads the rules from one or more comma separated rules file URIs, including any rules files
+// referenced by an '@include' directive, and merges them into a single ruleset.
+func loadRules(uri string, workdir string, settings *settings, dbg bool) (*acl.Rules, error) {
+	loader := rulesLoader{
+		workdir:  workdir,
+		settings: settings,
+		debug:    dbg,
+		loaded:   map[string]bool{},
+	}
+
+	// ... the custom HTTP headers are only sent to the hosts of the rules URIs
+	uris := []string{}
+	for _, u := range strings.Split(uri, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			uris = append(uris, u)
+
+			if h := host(u); h != "" && !slices.Contains(loader.origins, h) {
+				loader.origins = append(loader.origins, h)
+			}
+		}
+	}
+
+	for _, u := range uris {
+		if err := loader.load(u); err != nil {
+			return nil, err
+		}
+	}
+
+	rules, err := acl.MergeRules(loader.sources, dbg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(loader.revisions) > 0 {
+		rules.SetRevision(strings.Join(loader.revisions, ","))
+	}
+
+	return rules, nil
+}
+
+type rulesLoader struct {
+	workdir   string
+	settings  *settings
+	debug     bool
+	origins   []string
+	loaded    map[string]bool
+	stack     []string
+	sources   []acl.Source
+	revisions []string
+}
+
+// Loads a rules file and the rules files it includes (which precede it in the merged ruleset).
+// A rules file that has already been loaded (e.g. a common rules file included by two site rules
+// files) is skipped, but a rules file that includes itself (directly or indirectly) is an error.
+func (l *rulesLoader) load(uri string) error {
+	if slices.Contains(l.stack, uri) {
+		return fmt.Errorf("rules file %v includes itself (%v)", uri, strings.Join(append(l.stack, uri), " -> "))
+	}
+
+	if l.loaded[uri] {
+		return nil
+	}
+
+	index := len(l.loaded)
+	l.loaded[uri] = true
+	l.stack = append(l.stack, uri)
+
+	defer func() {
+		l.stack = l.stack[:len(l.stack)-1]
+	}()
+
+	ruleset, err := l.fetch(uri, index)
+	if err != nil {
+		return err
+	}
+
+	for _, include := range includes(ruleset) {
+		if err := l.load(resolveInclude(uri, include)); err != nil {
+			return err
+		}
+	}
+
+	l.sources = append(l.sources, acl.Source{URI: uri, Ruleset: ruleset})
+
+	return nil
+}
+
+// Fetches and verifies a rules file, falling back to the stashed copy if the rules file is
+// invalid or fails verification. The checksum and signature URI settings only apply to the
+// first rules file - additional rules files are verified against their own (default URI)
+// signature if a public key is configured. Remote additional rules files are refused if the
+// rules are verified with a checksum but not a public key.
+func (l *rulesLoader) fetch(uri string, index int) ([]byte, error) {
+	if s := l.settings; s != nil && index > 0 && isRemote(uri) {
+		if strings.TrimSpace(s.WildApricot.Rules.SHA256) != "" && strings.TrimSpace(s.WildApricot.Rules.PublicKey) == "" {
+			return nil, fmt.Errorf("unverified remote rules file %v (wild-apricot.rules.sha256 only verifies the first rules file)", uri)
+		}
+	}
+
+	stashed := stashFile(l.workdir, uri, index)
+	cache := loadHTTPCache(stashed)
+
+	revision := ""
+	options := l.settings.fetchOptions(uri, l.origins, l.workdir, cache)
+	options.Revision = &revision
+
+	ruleset, err := fetch(uri, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.debug {
+		filename := time.Now().Format("RULES 2006-01-02 15:04:05") + filepath.Ext(stashed)
 		path := filepath.Join(os.TempDir(), filename)
 		if f, err := os.Create(path); err != nil {
 			warnf("%v", err)
@@ -144,24 +316,65 @@ func getRules(uri string, workdir string, dbg bool) (*acl.Rules, error) {
 		}
 	}
 
-	if rules, err := acl.NewRules(ruleset, dbg); err != nil {
+	s := l.settings
+	if s != nil && index > 0 {
+		other := *s
+		other.WildApricot.Rules.SHA256 = ""
+		other.WildApricot.Rules.Signature = ""
+		s = &other
+	}
+
+	if err := verifyRules(ruleset, uri, l.origins, l.workdir, s); err != nil {
+		warnf("Rules file %v failed verification (%v)", uri, err)
+	} else if _, err := acl.ParseRules(uri, ruleset, l.debug); err != nil {
 		warnf("%v", err)
 	} else {
-		stash(ruleset, filepath.Join(workdir, "wild-apricot.grl"))
+		stash(ruleset, stashed)
+		cache.save()
+
+		if revision != "" {
+			infof("Using rules %v at commit %v", uri, revision)
+			l.revisions = append(l.revisions, revision)
+		}
 
-		return rules, nil
+		return ruleset, nil
 	}
 
 	// ... try load cached rules
-	stashed := filepath.Join(workdir, "wild-apricot.grl")
 	if ruleset, err := os.ReadFile(stashed); err != nil {
 		return nil, err
 	} else {
 		warnf("Using stashed 'grules' file (%v)", stashed)
-		return acl.NewRules(ruleset, dbg)
+		return ruleset, nil
 	}
 }
 
+// Returns the path of the stashed copy of the rules file i.e. wild-apricot.grl for a 'grule'
+// rules file, wild-apricot.yaml/wild-apricot.json for a policy file and wild-apricot.cel for a
+// CEL rules file. Additional (merged or included) rules files are stashed as wild-apricot-<hash>.grl,
+// etc., where <hash> is derived from the rules file URI so that the stashed copy of a rules file does
+// not depend on the order in which the rules files are loaded.
+func stashFile(workdir string, uri string, index int) string {
+	ext := ".grl"
+	switch acl.Format(uri) {
+	case ".yaml", ".yml":
+		ext = ".yaml"
+
+	case ".json":
+		ext = ".json"
+
+	case ".cel":
+		ext = ".cel"
+	}
+
+	if index > 0 {
+		hash := sha256.Sum256([]byte(uri))
+		return filepath.Join(workdir, fmt.Sprintf("wild-apricot-%v%v", hex.EncodeToString(hash[:])[:16], ext))
+	}
+
+	return filepath.Join(workdir, "wild-apricot"+ext)
+}
+
 func stash(bytes []byte, file string) {
 	if f, err := os.Create(file); err != nil {
 		warnf("Error creating stashed 'grules' file (%v)", err)
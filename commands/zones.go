@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Zones are the named door groups defined in uhppoted.conf, e.g.:
+//
+//	wild-apricot.zone.Workshop = Lathe, Bandsaw, Welding Bay
+type Zones map[string][]string
+
+func (z *Zones) UnmarshalConf(tag string, values map[string]string) (any, error) {
+	re := regexp.MustCompile(`^/(.*?)/$`)
+	match := re.FindStringSubmatch(tag)
+	if len(match) < 2 {
+		return z, fmt.Errorf("invalid 'conf' regular expression tag: %s", tag)
+	}
+
+	re, err := regexp.Compile(match[1])
+	if err != nil {
+		return z, err
+	}
+
+	if *z == nil {
+		*z = Zones{}
+	}
+
+	for key, value := range values {
+		if match := re.FindStringSubmatch(key); len(match) > 1 {
+			doors := []string{}
+			for _, d := range strings.Split(value, ",") {
+				if d = clean(d); d != "" {
+					doors = append(doors, d)
+				}
+			}
+
+			(*z)[strings.TrimSpace(match[1])] = doors
+		}
+	}
+
+	return z, nil
+}
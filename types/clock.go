@@ -0,0 +1,43 @@
+package types
+
+import (
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
+)
+
+// Clock is the source of the current time for the date based member functions and default card
+// validity. A nil Clock is the system clock.
+type Clock func() time.Time
+
+// AsOf returns a Clock fixed at the start of the given date e.g. to preview the ACL for a
+// future date.
+func AsOf(date core.Date) Clock {
+	t := time.Time(date)
+
+	return func() time.Time {
+		return t
+	}
+}
+
+func (c Clock) Now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+
+	return c()
+}
+
+func (c Clock) Today() core.Date {
+	now := c.Now()
+
+	return core.ToDate(now.Year(), now.Month(), now.Day())
+}
+
+// WithClock returns a copy of the member that uses the clock for the date based member functions
+// (e.g. DaysUntilExpiry).
+func WithClock(m Member, clock Clock) Member {
+	m.clock = clock
+
+	return m
+}
@@ -29,8 +29,9 @@ type MembersWithPIN struct {
 }
 
 type Member struct {
-	id         uint32
+	ID         uint32
 	Name       string
+	Email      string
 	CardNumber *CardNumber
 	PIN        uint32
 	Active     bool
@@ -40,6 +41,7 @@ type Member struct {
 	Groups     map[uint32]Group
 	Membership Membership
 	Fields     []Field
+	clock      Clock
 }
 
 type CardNumber uint32
@@ -124,6 +126,36 @@ func (m *Member) HasExpires() bool {
 	return m != nil && !m.Expires.IsZero()
 }
 
+// Returns the number of days from today until the membership 'expires' date, which is
+// negative if the membership has already expired. Returns 0 if the member does not have
+// an 'expires' date - use HasExpires to distinguish between 'no expiry date' and 'expires
+// today'. 'today' is taken from the member clock (see WithClock), defaulting to the system
+// clock.
+func (m *Member) DaysUntilExpiry() int64 {
+	if m != nil && !m.Expires.IsZero() {
+		return days(m.clock.Today(), m.Expires)
+	}
+
+	return 0
+}
+
+// Returns true if the membership expired more than the given number of days ago e.g.
+// ExpiredFor(14) is true from the 15th day after the 'expires' date. Returns false if the
+// member does not have an 'expires' date.
+func (m *Member) ExpiredFor(days any) bool {
+	if m != nil && !m.Expires.IsZero() {
+		switch v := days.(type) {
+		case int:
+			return m.DaysUntilExpiry() < -int64(v)
+
+		case int64:
+			return m.DaysUntilExpiry() < -v
+		}
+	}
+
+	return false
+}
+
 func (m *Member) IsActive() bool {
 	return m != nil && m.Active
 }
@@ -218,9 +250,9 @@ func MakeMemberList(contacts []wildapricot.Contact, memberGroups []wildapricot.M
 			if m.CardNumber != nil && *m.CardNumber > 0 && *m.CardNumber < 100000 && facilityCode != "" {
 				cardNo := fmt.Sprintf("%v%05v", facilityCode, m.CardNumber)
 				if v, err := strconv.ParseUint(cardNo, 10, 32); err != nil {
-					warnings.warnings = append(warnings.warnings, fmt.Sprintf("prepending facility code '%v' to card number '%v' for member %v (%v)", facilityCode, m.CardNumber, m.id, err))
+					warnings.warnings = append(warnings.warnings, fmt.Sprintf("prepending facility code '%v' to card number '%v' for member %v (%v)", facilityCode, m.CardNumber, m.ID, err))
 				} else {
-					warnings.info = append(warnings.info, fmt.Sprintf("prepending facility code '%v' to card %v for member %v\n", facilityCode, m.CardNumber, m.id))
+					warnings.info = append(warnings.info, fmt.Sprintf("prepending facility code '%v' to card %v for member %v\n", facilityCode, m.CardNumber, m.ID))
 					nn := uint32(v)
 					m.CardNumber = (*CardNumber)(&nn)
 
@@ -477,8 +509,9 @@ func (members *Members) asTableWithPIN() ([]string, [][]string) {
 
 func transcode(contact wildapricot.Contact, sysgroups []Group, fields map[field]string) (*Member, error) {
 	member := Member{
-		id:   contact.ID,
-		Name: fmt.Sprintf("%[1]s %[2]s", contact.FirstName, contact.LastName),
+		ID:    contact.ID,
+		Name:  fmt.Sprintf("%[1]s %[2]s", contact.FirstName, contact.LastName),
+		Email: contact.Email,
 		Membership: Membership{
 			ID:   contact.MembershipLevel.ID,
 			Name: contact.MembershipLevel.Name,
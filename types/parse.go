@@ -0,0 +1,231 @@
+package types
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	core "github.com/uhppoted/uhppote-core/types"
+)
+
+// Parses a members list in the TSV format generated by get-members, i.e. a header row with
+// the 'Name', 'Card Number', (optional) 'PIN', 'Membership', 'Active', 'Suspended',
+// 'Registered' and 'Expires' columns followed by a Y/N column for each member group. An
+// optional 'Member ID' column is also accepted. The membership level and group IDs are not
+// part of the TSV format so groups are assigned sequential IDs in column order.
+func ParseMembersTSV(r io.Reader) (*Members, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	} else if len(rows) < 1 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	columns := map[string]int{}
+	groups := map[int]Group{}
+
+	for i, h := range rows[0] {
+		switch k := normalise(h); k {
+		case "memberid", "name", "cardnumber", "pin", "membership", "active", "suspended", "registered", "expires":
+			if _, ok := columns[k]; ok {
+				return nil, fmt.Errorf("duplicate column '%v'", h)
+			}
+			columns[k] = i
+
+		default:
+			if k == "" {
+				return nil, fmt.Errorf("blank column %v", i+1)
+			}
+
+			groups[i] = Group{
+				ID:    uint32(len(groups) + 1),
+				Name:  strings.TrimSpace(h),
+				index: uint32(len(groups) + 1),
+			}
+		}
+	}
+
+	for _, k := range []string{"name", "cardnumber"} {
+		if _, ok := columns[k]; !ok {
+			return nil, fmt.Errorf("missing '%v' column", k)
+		}
+	}
+
+	members := Members{
+		Groups:  []Group{},
+		Members: []Member{},
+	}
+
+	for i := range rows[0] {
+		if g, ok := groups[i]; ok {
+			members.Groups = append(members.Groups, g)
+		}
+	}
+
+	for i, row := range rows[1:] {
+		field := func(k string) string {
+			if ix, ok := columns[k]; ok && ix < len(row) {
+				return strings.TrimSpace(row[ix])
+			}
+
+			return ""
+		}
+
+		yes := func(k string) bool {
+			return strings.EqualFold(field(k), "Y")
+		}
+
+		m := Member{
+			Name:       field("name"),
+			Active:     yes("active"),
+			Suspended:  yes("suspended"),
+			Membership: Membership{Name: field("membership")},
+			Groups:     map[uint32]Group{},
+		}
+
+		if v := field("memberid"); v != "" {
+			if id, err := strconv.ParseUint(v, 10, 32); err != nil {
+				return nil, fmt.Errorf("row %v: invalid member ID '%v'", i+2, v)
+			} else {
+				m.ID = uint32(id)
+			}
+		}
+
+		if v := field("cardnumber"); v != "" {
+			if card, err := strconv.ParseUint(v, 10, 32); err != nil {
+				return nil, fmt.Errorf("row %v: invalid card number '%v'", i+2, v)
+			} else {
+				c := CardNumber(card)
+				m.CardNumber = &c
+			}
+		}
+
+		if v := field("pin"); v != "" {
+			if pin, err := strconv.ParseUint(v, 10, 32); err != nil {
+				return nil, fmt.Errorf("row %v: invalid PIN '%v'", i+2, v)
+			} else {
+				m.PIN = uint32(pin)
+			}
+		}
+
+		if v := field("registered"); v != "" {
+			if date, err := core.ParseDate(v); err != nil {
+				return nil, fmt.Errorf("row %v: invalid 'registered' date '%v'", i+2, v)
+			} else {
+				m.Registered = date
+			}
+		}
+
+		if v := field("expires"); v != "" {
+			if date, err := core.ParseDate(v); err != nil {
+				return nil, fmt.Errorf("row %v: invalid 'expires' date '%v'", i+2, v)
+			} else {
+				m.Expires = date
+			}
+		}
+
+		for ix, g := range groups {
+			if ix < len(row) && strings.EqualFold(strings.TrimSpace(row[ix]), "Y") {
+				m.Groups[g.ID] = g
+			}
+		}
+
+		members.Members = append(members.Members, m)
+	}
+
+	return &members, nil
+}
+
+// Parses a members list formatted as a JSON array of members, e.g.:
+//
+//	[
+//	  { "member-id": 12345, "name": "Albus Dumbledore", "card-number": 10058400, "pin": 7531,
+//	    "membership": "Staff", "active": true, "suspended": false,
+//	    "registered": "2020-01-01", "expires": "2026-12-31",
+//	    "groups": [ "Teacher" ], "fields": { "Patronus": "Phoenix" } }
+//	]
+//
+// Groups are assigned sequential IDs in the order in which they first appear.
+func ParseMembersJSON(bytes []byte) (*Members, error) {
+	list := []struct {
+		ID         uint32         `json:"member-id"`
+		Name       string         `json:"name"`
+		Email      string         `json:"email"`
+		CardNumber *uint32        `json:"card-number"`
+		PIN        uint32         `json:"pin"`
+		Membership string         `json:"membership"`
+		Active     bool           `json:"active"`
+		Suspended  bool           `json:"suspended"`
+		Registered core.Date      `json:"registered"`
+		Expires    core.Date      `json:"expires"`
+		Groups     []string       `json:"groups"`
+		Fields     map[string]any `json:"fields"`
+	}{}
+
+	if err := json.Unmarshal(bytes, &list); err != nil {
+		return nil, err
+	}
+
+	members := Members{
+		Groups:  []Group{},
+		Members: []Member{},
+	}
+
+	groups := map[string]Group{}
+
+	for _, v := range list {
+		m := Member{
+			ID:         v.ID,
+			Name:       v.Name,
+			Email:      v.Email,
+			PIN:        v.PIN,
+			Active:     v.Active,
+			Suspended:  v.Suspended,
+			Registered: v.Registered,
+			Expires:    v.Expires,
+			Membership: Membership{Name: v.Membership},
+			Groups:     map[uint32]Group{},
+			Fields:     []Field{},
+		}
+
+		if v.CardNumber != nil {
+			c := CardNumber(*v.CardNumber)
+			m.CardNumber = &c
+		}
+
+		for _, name := range v.Groups {
+			g, ok := groups[normalise(name)]
+			if !ok {
+				g = Group{
+					ID:    uint32(len(groups) + 1),
+					Name:  name,
+					index: uint32(len(groups) + 1),
+				}
+
+				groups[normalise(name)] = g
+				members.Groups = append(members.Groups, g)
+			}
+
+			m.Groups[g.ID] = g
+		}
+
+		for k, f := range v.Fields {
+			m.Fields = append(m.Fields, Field{
+				ID:    k,
+				Name:  k,
+				Value: f,
+			})
+		}
+
+		members.Members = append(members.Members, m)
+	}
+
+	return &members, nil
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	core "github.com/uhppoted/uhppote-core/types"
+)
+
+func TestParseMembersTSV(t *testing.T) {
+	tsv := "Member ID\tName\tCard Number\tPIN\tMembership\tActive\tSuspended\tRegistered\tExpires\tStaff\tGryffindor\n" +
+		"12345\tAlbus Dumbledore\t1000001\t7531\tStaff\tY\tN\t1880-02-29\t\tY\tN\n" +
+		"\tHarry Potter\t6000001\t\tStudent\tY\tN\t\t2021-06-30\tN\tY\n"
+
+	card1 := CardNumber(1000001)
+	card2 := CardNumber(6000001)
+	staff := Group{ID: 1, Name: "Staff", index: 1}
+	gryffindor := Group{ID: 2, Name: "Gryffindor", index: 2}
+
+	expected := Members{
+		Groups: []Group{staff, gryffindor},
+		Members: []Member{
+			Member{
+				ID:         12345,
+				Name:       "Albus Dumbledore",
+				CardNumber: &card1,
+				PIN:        7531,
+				Active:     true,
+				Registered: core.MustParseDate("1880-02-29"),
+				Membership: Membership{Name: "Staff"},
+				Groups:     map[uint32]Group{1: staff},
+			},
+			Member{
+				Name:       "Harry Potter",
+				CardNumber: &card2,
+				Active:     true,
+				Expires:    core.MustParseDate("2021-06-30"),
+				Membership: Membership{Name: "Student"},
+				Groups:     map[uint32]Group{2: gryffindor},
+			},
+		},
+	}
+
+	members, err := ParseMembersTSV(strings.NewReader(tsv))
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if !reflect.DeepEqual(*members, expected) {
+		t.Errorf("Incorrect members\n   expected:%+v\n   got:     %+v", expected, *members)
+	}
+}
+
+func TestParseMembersTSVWithInvalidCardNumber(t *testing.T) {
+	tsv := "Name\tCard Number\nAlbus Dumbledore\tqwerty\n"
+
+	if _, err := ParseMembersTSV(strings.NewReader(tsv)); err == nil {
+		t.Errorf("Expected error parsing invalid card number, got %v", err)
+	}
+}
+
+func TestParseMembersJSON(t *testing.T) {
+	bytes := []byte(`[
+	  { "member-id": 12345, "name": "Albus Dumbledore", "card-number": 1000001, "membership": "Staff", "active": true, 
+	    "registered": "1880-02-29", "groups": [ "Staff" ], "fields": { "Patronus": "Phoenix" } },
+	  { "name": "Harry Potter", "card-number": 6000001, "membership": "Student", "active": true, "expires": "2021-06-30", 
+	    "groups": [ "Gryffindor", "staff" ] }
+	]`)
+
+	card1 := CardNumber(1000001)
+	card2 := CardNumber(6000001)
+	staff := Group{ID: 1, Name: "Staff", index: 1}
+	gryffindor := Group{ID: 2, Name: "Gryffindor", index: 2}
+
+	expected := Members{
+		Groups: []Group{staff, gryffindor},
+		Members: []Member{
+			Member{
+				ID:         12345,
+				Name:       "Albus Dumbledore",
+				CardNumber: &card1,
+				Active:     true,
+				Registered: core.MustParseDate("1880-02-29"),
+				Membership: Membership{Name: "Staff"},
+				Groups:     map[uint32]Group{1: staff},
+				Fields:     []Field{Field{ID: "Patronus", Name: "Patronus", Value: "Phoenix"}},
+			},
+			Member{
+				Name:       "Harry Potter",
+				CardNumber: &card2,
+				Active:     true,
+				Expires:    core.MustParseDate("2021-06-30"),
+				Membership: Membership{Name: "Student"},
+				Groups:     map[uint32]Group{1: staff, 2: gryffindor},
+				Fields:     []Field{},
+			},
+		},
+	}
+
+	members, err := ParseMembersJSON(bytes)
+	if err != nil {
+		t.Fatalf("Unexpected error (%v)", err)
+	}
+
+	if !reflect.DeepEqual(*members, expected) {
+		t.Errorf("Incorrect members\n   expected:%+v\n   got:     %+v", expected, *members)
+	}
+}
@@ -3,6 +3,9 @@ package types
 import (
 	"regexp"
 	"strings"
+	"time"
+
+	core "github.com/uhppoted/uhppote-core/types"
 )
 
 func normalise(v string) string {
@@ -10,3 +13,14 @@ func normalise(v string) string {
 
 	return re.ReplaceAllString(strings.ToLower(v), "")
 }
+
+// Returns the number of calendar days from 'from' to 'to' (negative if 'to' is before 'from').
+func days(from, to core.Date) int64 {
+	p := time.Time(from)
+	q := time.Time(to)
+
+	t0 := time.Date(p.Year(), p.Month(), p.Day(), 0, 0, 0, 0, time.UTC)
+	t1 := time.Date(q.Year(), q.Month(), q.Day(), 0, 0, 0, 0, time.UTC)
+
+	return int64(t1.Sub(t0).Hours() / 24)
+}
@@ -276,6 +276,44 @@ func getMemberGroups(accountId uint32, token string, pageSize uint32, page uint3
 	return groups, nil
 }
 
+func GetMembershipLevels(accountId uint32, token string, api API) ([]MembershipLevel, error) {
+	uri := fmt.Sprintf("https://api.wildapricot.org/v2.2/accounts/%[1]v/membershiplevels", accountId)
+
+	rq, _ := http.NewRequest("GET", uri, nil)
+	rq.Header.Set("Authorization", "Bearer "+token)
+	rq.Header.Set("Accept", "application/json")
+	rq.Header.Set("Accept-Encoding", "gzip")
+
+	response, err := get(rq, api.Timeout, api.Retries, api.Delay)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	reader := response.Body
+	if strings.ToLower(response.Header.Get("Content-Encoding")) == "gzip" {
+		reader, err = gzip.NewReader(response.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := []MembershipLevel{}
+	if err := json.Unmarshal(body, &levels); err != nil {
+		return nil, err
+	}
+
+	log.Infof("retrieved %v membership levels", len(levels))
+
+	return levels, nil
+}
+
 func GetUpdated(accountId uint32, token string, timestamp time.Time, api API) (int, error) {
 	parameters := url.Values{}
 	parameters.Set("$async", "false")